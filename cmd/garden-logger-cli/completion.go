@@ -0,0 +1,291 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"garden-logger/internal"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// completer suggests values for a word, prefix is what has been typed of it so far
+type completer func(a *app, prefix string) []string
+
+// positionalCompleters lists the completer for each positional argument of a command
+var positionalCompleters = map[string][]completer{
+	"ls":         {completeDirs},
+	"tree":       {completeDirs},
+	"mkdir":      {completeDirs},
+	"new":        {completeDirs},
+	"open":       {completeEntries},
+	"mv":         {completeEntries, completeDirs},
+	"rm":         {completeEntries},
+	"rename":     {completeEntries},
+	"reorder":    {completeEntries, completeWords("up", "down", "top", "bottom")},
+	"index":      {completeWords("apply", "remove", "validate", "repair"), completeDirs},
+	"template":   {completeWords("list", "render"), completeTemplates},
+	"archive":    {completeEntries},
+	"missing":    {completeWords("day", "week", "month", "quarter", "year")},
+	"append":     {completeEntries},
+	"pin":        {completeEntries},
+	"tagged":     {completeTags},
+	"links":      {completeLinks},
+	"help":       {completeCommands},
+	"completion": {completeWords("bash", "zsh", "fish")},
+}
+
+// flagCompleters suggests values for flags that take one
+var flagCompleters = map[string]completer{
+	"template":  completeTemplates,
+	"on-exists": completeWords("open", "suffix", "abort"),
+	"position":  completeWords("top", "bottom"),
+	"date":      completeWords("today", "yesterday", "tomorrow"),
+	"from":      completeWords("today", "yesterday", "tomorrow"),
+	"to":        completeWords("today", "yesterday", "tomorrow"),
+}
+
+var globalFlags = []string{"-v", "--json", "--pick", "--dry-run", "--read-only"}
+
+func runCompletion(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	switch positional[0] {
+	case "bash":
+		io.WriteString(os.Stdout, bashCompletion)
+	case "zsh":
+		io.WriteString(os.Stdout, zshCompletion)
+	case "fish":
+		io.WriteString(os.Stdout, fishCompletion)
+	default:
+		return internal.Result{}, fmt.Errorf("%w: unknown shell %q (expected bash, zsh or fish)", internal.ErrUsage, positional[0])
+	}
+	return internal.Result{}, nil
+}
+
+// runComplete prints one candidate per line for the last of args, the words typed after the program name.
+// It never fails, a garden that can't be read just has nothing to suggest.
+func runComplete(a *app, cmd *command, args []string) (internal.Result, error) {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	a.json = false
+
+	for _, candidate := range completeArgs(a, args) {
+		fmt.Println(candidate)
+	}
+	return internal.Result{}, nil
+}
+
+func completeArgs(a *app, args []string) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	current := strings.ReplaceAll(args[len(args)-1], `\ `, " ")
+	words := args[:len(args)-1]
+
+	for len(words) > 0 && isFlagArg(words[0]) {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		if strings.HasPrefix(current, "-") {
+			return filterPrefix(globalFlags, current)
+		}
+		return completeCommands(a, current)
+	}
+
+	target := findCommand(words[0])
+	if target == nil {
+		return nil
+	}
+	fs := target.flagSet()
+
+	var positional []string
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		if word == "--" {
+			positional = append(positional, words[i+1:]...)
+			break
+		}
+		if !isFlagArg(word) {
+			positional = append(positional, word)
+			continue
+		}
+
+		name := strings.TrimLeft(word, "-")
+		if strings.Contains(name, "=") || isBoolFlag(fs, name) {
+			continue
+		}
+		if i+1 == len(words) {
+			if complete, ok := flagCompleters[name]; ok {
+				return complete(a, current)
+			}
+			return nil
+		}
+		i++
+	}
+
+	if strings.HasPrefix(current, "-") {
+		var names []string
+		fs.VisitAll(func(f *flag.Flag) { names = append(names, "--"+f.Name) })
+		return filterPrefix(names, current)
+	}
+
+	completers := positionalCompleters[target.name]
+	if len(positional) >= len(completers) {
+		return nil
+	}
+	return completers[len(positional)](a, current)
+}
+
+func completeCommands(a *app, prefix string) []string {
+	var names []string
+	for _, cmd := range commands {
+		if !cmd.hidden() {
+			names = append(names, cmd.name)
+		}
+	}
+	return filterPrefix(names, prefix)
+}
+
+func completeWords(words ...string) completer {
+	return func(a *app, prefix string) []string {
+		return filterPrefix(words, prefix)
+	}
+}
+
+func completeTemplates(a *app, prefix string) []string {
+	if err := a.load(); err != nil {
+		return nil
+	}
+	templates, err := a.notes.ListTemplates()
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, template := range templates {
+		names = append(names, template.Name)
+	}
+	return filterPrefix(names, prefix)
+}
+
+func completeTags(a *app, prefix string) []string {
+	if err := a.load(); err != nil {
+		return nil
+	}
+	tags, err := a.notes.Tags()
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Tag)
+	}
+	return filterPrefix(names, strings.TrimPrefix(prefix, "#"))
+}
+
+func completeLinks(a *app, prefix string) []string {
+	return append(filterPrefix([]string{"check"}, prefix), completeEntries(a, prefix)...)
+}
+
+func completeDirs(a *app, prefix string) []string {
+	return completePaths(a, prefix, true)
+}
+
+func completeEntries(a *app, prefix string) []string {
+	return completePaths(a, prefix, false)
+}
+
+// completePaths suggests the children of the directory typed so far, resolved fuzzily, without their indexes.
+// Directories are always offered so the path can be completed further.
+func completePaths(a *app, prefix string, dirsOnly bool) []string {
+	if err := a.load(); err != nil {
+		return nil
+	}
+
+	dirPart, base := "", prefix
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dirPart, base = prefix[:i], prefix[i+1:]
+	}
+
+	dirPath, err := a.notes.ResolveDir(dirPart)
+	if err != nil {
+		return nil
+	}
+	dir, err := a.notes.LoadDirectory(dirPath)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, entry := range dir.Entries {
+		if dirsOnly && !entry.IsDir {
+			continue
+		}
+		name := entry.Name + entry.Ext
+		if !strings.HasPrefix(strings.ToLower(name), strings.ToLower(base)) &&
+			!strings.HasPrefix(strings.ToLower(entry.String()), strings.ToLower(base)) {
+			continue
+		}
+
+		candidate := filepath.Join(internal.StripIndexes(dirPath), name)
+		if entry.IsDir {
+			candidate += "/"
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+func filterPrefix(values []string, prefix string) []string {
+	var matches []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			matches = append(matches, value)
+		}
+	}
+	return matches
+}
+
+const bashCompletion = `# garden-logger-cli bash completion, load with: source <(garden-logger-cli completion bash)
+_garden_logger_cli() {
+	local IFS=$'\n'
+	local candidates=($(garden-logger-cli __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+	local candidate
+	COMPREPLY=()
+	for candidate in "${candidates[@]}"; do
+		COMPREPLY+=("$(printf '%q' "$candidate")")
+	done
+	if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == */ ]]; then
+		compopt -o nospace
+	fi
+}
+complete -F _garden_logger_cli garden-logger-cli
+`
+
+const zshCompletion = `#compdef garden-logger-cli
+# garden-logger-cli zsh completion, load with: source <(garden-logger-cli completion zsh)
+_garden_logger_cli() {
+	local -a candidates dirs others
+	candidates=("${(@f)$(garden-logger-cli __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	dirs=(${(M)candidates:#*/})
+	others=(${candidates:#*/})
+	compadd -U -S '' -- "${dirs[@]}"
+	compadd -U -- "${others[@]}"
+}
+compdef _garden_logger_cli garden-logger-cli
+`
+
+const fishCompletion = `# garden-logger-cli fish completion, load with: garden-logger-cli completion fish | source
+function __garden_logger_cli_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    garden-logger-cli __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c garden-logger-cli -f -a '(__garden_logger_cli_complete)'
+`
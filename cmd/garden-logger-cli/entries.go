@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"garden-logger/internal"
+	"os"
+	"strings"
+)
+
+func lsFlags(fs *flag.FlagSet) {
+	fs.Bool("l", false, "Show index and type columns")
+	fs.Bool("meta", false, "Show the status and tags from each note's frontmatter")
+}
+
+func runLs(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 0)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	long := flagBool(fs, "l")
+	meta := flagBool(fs, "meta")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	dirPath := ""
+	if len(positional) > 0 {
+		dirPath, err = a.notes.ResolveDir(positional[0])
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+
+	if err := a.nav.NavigateTo(dirPath); err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitEntries(a.nav.CurrentDirectory().Entries, long, meta)
+	return internal.Result{}, nil
+}
+
+func treeFlags(fs *flag.FlagSet) {
+	fs.Int("depth", -1, "Maximum depth to descend, -1 for unlimited")
+	fs.Bool("dirs-only", false, "Leave out notes")
+	fs.Bool("strip-index", false, "Show names without their index")
+	fs.Bool("markdown", false, "Print a nested markdown list with wikilinks to the notes")
+}
+
+func runTree(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 0)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	depth := flagInt(fs, "depth")
+	dirsOnly := flagBool(fs, "dirs-only")
+	stripIndex := flagBool(fs, "strip-index")
+	markdown := flagBool(fs, "markdown")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	dirPath := ""
+	if len(positional) > 0 {
+		dirPath, err = a.notes.ResolveDir(positional[0])
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+
+	tree, err := a.notes.BuildTree(dirPath, depth, dirsOnly)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	tree.Entry = &internal.Entry{EntryIndex: -1, Name: ".", IsDir: true}
+	if dirPath != "" {
+		if _, tree.Entry, err = a.notes.LoadEntryAt(dirPath); err != nil {
+			return internal.Result{}, err
+		}
+	}
+
+	a.emitTree(tree, treeStyle{stripIndex: stripIndex, markdown: markdown})
+	return internal.Result{}, nil
+}
+
+type treeStyle struct {
+	stripIndex bool
+	markdown   bool
+}
+
+func (t treeStyle) name(node *internal.TreeNode) string {
+	if node.Path == "" {
+		return "."
+	}
+	name := node.Entry.String()
+	if t.stripIndex {
+		name = node.Entry.Name + node.Entry.Ext
+	}
+	if node.Entry.IsDir {
+		name += "/"
+	}
+	return name
+}
+
+// summary describes a directory's note count and indexing
+func (t treeStyle) summary(node *internal.TreeNode) string {
+	notes := fmt.Sprintf("%d notes", node.Notes)
+	if node.Notes == 1 {
+		notes = "1 note"
+	}
+	if node.IsIndexed {
+		return notes + ", numeric"
+	}
+	return notes
+}
+
+func (t treeStyle) print(nodes []*internal.TreeNode, prefix string) {
+	for i, node := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		line := prefix + branch + t.name(node)
+		if node.Entry.IsDir {
+			line += " [" + t.summary(node) + "]"
+		}
+		fmt.Println(line)
+		t.print(node.Children, prefix+indent)
+	}
+}
+
+func (t treeStyle) printMarkdown(nodes []*internal.TreeNode, indent string) {
+	for _, node := range nodes {
+		switch {
+		case node.Entry.IsDir:
+			fmt.Printf("%s- **%s** (%s)\n", indent, t.name(node), t.summary(node))
+		case node.Entry.Ext == ".md":
+			fmt.Printf("%s- [[%s]]\n", indent, node.Entry.Name)
+		default:
+			fmt.Printf("%s- %s\n", indent, t.name(node))
+		}
+		t.printMarkdown(node.Children, indent+"  ")
+	}
+}
+
+func runMkdir(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	dir, err := a.notes.EnsureDirectory(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitPath(dir.Path)
+	return internal.Result{Outcome: internal.OutcomeCreated, Path: dir.Path}, nil
+}
+
+func runMv(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 2)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	newPath, err := a.notes.MoveEntry(positional[0], positional[1])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitPath(newPath)
+	return internal.Result{Outcome: internal.OutcomeDone, Path: newPath}, nil
+}
+
+func runArchive(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	newPath, err := a.notes.ArchiveEntry(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitPath(newPath)
+	return internal.Result{Outcome: internal.OutcomeDone, Path: newPath}, nil
+}
+
+func rmFlags(fs *flag.FlagSet) {
+	fs.Bool("yes", false, "Don't ask to type the entry's name to confirm")
+	fs.Bool("recursive", false, "Delete directories along with their contents")
+}
+
+func runRm(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	yes := flagBool(fs, "yes")
+	recursive := flagBool(fs, "recursive")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	dir, entry, err := a.notes.LoadEntryAt(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if !yes {
+		fmt.Fprintf(os.Stderr, "Type %q to delete it: ", entry.Name)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != entry.Name {
+			return internal.Result{}, fmt.Errorf("deletion of %s not confirmed", entry.String())
+		}
+	}
+
+	entryPath := a.relPath(entry.FilePath())
+	if recursive {
+		err = dir.DeleteEntryRecursive(entry)
+	} else {
+		err = dir.DeleteEntry(entry)
+	}
+	if err != nil {
+		return internal.Result{}, err
+	}
+	return internal.Result{Outcome: internal.OutcomeDone, Path: entryPath}, nil
+}
+
+func runRename(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 2)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	_, entry, err := a.notes.LoadEntryAt(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := entry.Rename(strings.Join(positional[1:], " ")); err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitPath(a.relPath(entry.FilePath()))
+	return internal.Result{Outcome: internal.OutcomeDone, Path: a.relPath(entry.FilePath())}, nil
+}
+
+func runReorder(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 2)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	dir, entry, err := a.notes.LoadEntryAt(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := dir.Reorder(entry, positional[1]); err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitPath(a.relPath(entry.FilePath()))
+	return internal.Result{Outcome: internal.OutcomeDone, Path: a.relPath(entry.FilePath())}, nil
+}
+
+func batchFlags(fs *flag.FlagSet) {
+	fs.Bool("check", false, "Only validate the operations")
+}
+
+func runBatch(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	if _, err := cmd.parse(fs, args, 0); err != nil {
+		return internal.Result{}, err
+	}
+
+	check := flagBool(fs, "check")
+
+	ops, err := internal.ParseBatch(os.Stdin)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	if check {
+		if err := a.notes.PlanBatch(ops); err != nil {
+			return internal.Result{}, err
+		}
+		a.emitValid(true, fmt.Sprintf("%d operations are valid", len(ops)))
+		return internal.Result{}, nil
+	}
+
+	if err := a.notes.ApplyBatch(ops); err != nil {
+		return internal.Result{}, err
+	}
+	a.emitMessage("Applied %d operations", len(ops))
+	if len(ops) == 0 {
+		return internal.Result{}, nil
+	}
+	return internal.Result{Outcome: internal.OutcomeDone}, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"garden-logger/internal"
+	"path/filepath"
+	"strings"
+)
+
+func initFlags(fs *flag.FlagSet) {
+	fs.String("folders", strings.Join(internal.DefaultFolders, ","), "Comma separated top level folders, the first becomes the inbox and the last the archive")
+	fs.Bool("force", false, "Scaffold into a non-empty root and overwrite an existing config file")
+}
+
+func runInit(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 0)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	foldersArg := flagString(fs, "folders")
+	force := flagBool(fs, "force")
+
+	var folders []string
+	for _, folder := range strings.Split(foldersArg, ",") {
+		if folder = strings.TrimSpace(folder); folder != "" {
+			folders = append(folders, folder)
+		}
+	}
+	if len(folders) == 0 {
+		return internal.Result{}, fmt.Errorf("%w: --folders needs at least one folder", internal.ErrUsage)
+	}
+
+	config, err := internal.LoadConfig()
+	if err != nil {
+		if len(positional) == 0 || !errors.Is(err, internal.ErrConfig) {
+			return internal.Result{}, err
+		}
+		config = internal.DefaultConfig()
+	}
+	if len(positional) > 0 {
+		config.RootDir, err = filepath.Abs(positional[0])
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+	a.use(config)
+
+	if err := internal.InitGarden(a.notes, folders, force); err != nil {
+		return internal.Result{}, err
+	}
+
+	configPath, written, err := a.notes.WriteConfig(force)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitMessage("Garden ready in %s", config.RootDir)
+	if written {
+		a.emitMessage("Wrote config to %s", configPath)
+	} else {
+		a.emitMessage("Kept existing config %s, use --force to overwrite it", configPath)
+	}
+	return internal.Result{Outcome: internal.OutcomeCreated}, nil
+}
+
+func doctorFlags(fs *flag.FlagSet) {
+	fs.Bool("fix", false, "Apply the fixes that can't lose any content")
+}
+
+func runDoctor(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	if _, err := cmd.parse(fs, args, 0); err != nil {
+		return internal.Result{}, err
+	}
+
+	fix := flagBool(fs, "fix")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	findings, err := a.notes.Diagnose()
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	// Later findings sit deeper in the garden, fixing them first keeps the paths of the earlier ones valid
+	fixed := make([]bool, len(findings))
+	if fix {
+		for i := len(findings) - 1; i >= 0; i-- {
+			if !findings[i].Fixable() {
+				continue
+			}
+			if err := findings[i].Apply(); err != nil {
+				return internal.Result{}, fmt.Errorf("failed to fix %s: %w", displayPath(findings[i].Path), err)
+			}
+			fixed[i] = true
+		}
+	}
+
+	a.emitFindings(findings, fixed)
+
+	problems, fixes := 0, 0
+	for i, finding := range findings {
+		if fixed[i] {
+			fixes++
+		} else if finding.Severity >= internal.SeverityWarning {
+			problems++
+		}
+	}
+	if problems > 0 {
+		return internal.Result{}, fmt.Errorf("%w: %d problems found", internal.ErrValidation, problems)
+	}
+	if fixes > 0 {
+		return internal.Result{Outcome: internal.OutcomeDone}, nil
+	}
+	return internal.Result{}, nil
+}
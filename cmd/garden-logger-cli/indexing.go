@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"garden-logger/internal"
+	"time"
+)
+
+func runIndex(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	dirPath := ""
+	if len(positional) > 1 {
+		dirPath, err = a.notes.ResolveDirExact(positional[1])
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+
+	if err := a.nav.NavigateTo(dirPath); err != nil {
+		return internal.Result{}, err
+	}
+	dir := a.nav.CurrentDirectory()
+
+	switch positional[0] {
+	case "apply":
+		err = dir.ApplyNumericIndexing()
+	case "remove":
+		err = dir.RemoveIndexing()
+	case "repair":
+		err = dir.RepairIndexing()
+	case "validate":
+		if !dir.IsIndexed {
+			a.emitValid(true, displayPath(dir.Path)+" is not indexed")
+			return internal.Result{}, nil
+		}
+		if err := dir.ValidateIndexing(); err != nil {
+			return internal.Result{}, err
+		}
+		a.emitValid(true, displayPath(dir.Path)+" is valid")
+		return internal.Result{}, nil
+	default:
+		return internal.Result{}, fmt.Errorf("%w: unknown index action %q (expected apply, remove, validate or repair)", internal.ErrUsage, positional[0])
+	}
+	if err != nil {
+		return internal.Result{}, err
+	}
+	return internal.Result{Outcome: internal.OutcomeDone, Path: dir.Path}, nil
+}
+
+func templateFlags(fs *flag.FlagSet) {
+	fs.String("title", "", "Title to render the template with, defaults to the date")
+	fs.String("date", "today", "Date to render the template for")
+}
+
+func runTemplate(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	title := flagString(fs, "title")
+	dateArg := flagString(fs, "date")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	switch positional[0] {
+	case "list":
+		templates, err := a.notes.ListTemplates()
+		if err != nil {
+			return internal.Result{}, err
+		}
+		a.emitEntries(templates, false, false)
+		return internal.Result{}, nil
+	case "render":
+		if len(positional) < 2 {
+			return internal.Result{}, fmt.Errorf("%w: garden-logger-cli template render <name>", internal.ErrUsage)
+		}
+
+		date, err := internal.ParseDateArg(dateArg, time.Now())
+		if err != nil {
+			return internal.Result{}, err
+		}
+		if title == "" {
+			title = date.Format("2006-01-02")
+		}
+
+		templatePath, err := a.notes.ResolveTemplate(positional[1])
+		if err != nil {
+			return internal.Result{}, err
+		}
+		content, err := a.notes.RenderTemplate(templatePath, title, date)
+		if err != nil {
+			return internal.Result{}, err
+		}
+		a.emitContent(content)
+		return internal.Result{}, nil
+	default:
+		return internal.Result{}, fmt.Errorf("%w: unknown template action %q (expected list or render)", internal.ErrUsage, positional[0])
+	}
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
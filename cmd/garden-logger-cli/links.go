@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"garden-logger/internal"
+)
+
+func linksFlags(fs *flag.FlagSet) {
+	fs.Bool("fix", false, "With check, rewrite links that point to an old index")
+}
+
+func runLinks(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	fix := flagBool(fs, "fix")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	if positional[0] == "check" {
+		return runLinksCheck(a, fix)
+	}
+
+	notePath, err := a.notes.ResolveNote(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	backlinks, outgoing, err := a.notes.NoteLinks(notePath)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitLinks(backlinks, outgoing)
+	return internal.Result{Path: notePath}, nil
+}
+
+func runLinksCheck(a *app, fix bool) (internal.Result, error) {
+	broken, err := a.notes.CheckLinks()
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	fixes := 0
+	if fix {
+		fixes, err = a.notes.FixLinks(broken)
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+
+	a.emitBrokenLinks(broken, fix)
+
+	problems := len(broken)
+	if fix {
+		problems -= fixes
+	}
+	if problems > 0 {
+		return internal.Result{}, fmt.Errorf("%w: %d broken links found", internal.ErrValidation, problems)
+	}
+	if fixes > 0 {
+		return internal.Result{Outcome: internal.OutcomeDone}, nil
+	}
+	return internal.Result{}, nil
+}
@@ -5,13 +5,20 @@ import (
 	"flag"
 	"fmt"
 	"garden-logger/internal"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 )
 
 func main() {
-	var verbose bool
+	var verbose, jsonOutput, pick, dryRun, readOnly bool
 	flag.BoolVar(&verbose, "v", false, "Enable verbose logging")
+	flag.BoolVar(&jsonOutput, "json", false, "Print machine-readable JSON on stdout")
+	flag.BoolVar(&pick, "pick", false, "Pick from a menu when a path matches several entries instead of failing")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print the renames, creates and deletes a command would make without making them")
+	flag.BoolVar(&readOnly, "read-only", false, "Refuse every command that would change the garden")
+	flag.Usage = printUsage
 	flag.Parse()
 
 	internal.InitLogger(verbose)
@@ -19,76 +26,268 @@ func main() {
 	args := flag.Args()
 	if len(args) == 0 {
 		printUsage()
-		os.Exit(1)
+		os.Exit(internal.ExitUsage)
 	}
 
-	if err := handleCommand(args); err != nil {
-		var launchErr internal.LaunchSuccessError
-		if errors.As(err, &launchErr) {
-			os.Exit(0)
-		}
+	writeMode := internal.WriteEnabled
+	if dryRun {
+		writeMode = internal.WriteDryRun
+	}
+	if readOnly {
+		writeMode = internal.WriteReadOnly
+	}
+
+	a := &app{json: jsonOutput, pick: pick, writeMode: writeMode, resp: &response{Command: args[0]}}
+	result, err := handleCommand(a, args)
+	if errors.Is(err, flag.ErrHelp) {
+		err = nil
+	}
+	if errors.Is(err, internal.ErrCancelled) {
+		result, err = internal.Result{Outcome: internal.OutcomeCancelled}, nil
+	}
+	a.finish(result, err)
+
+	if err != nil {
 		slog.Error("CLI Error", "error", err)
-		os.Exit(1)
 	}
+	os.Exit(internal.ExitCode(result, err))
+}
+
+// app holds the services shared by every command, loaded once a command has parsed its arguments
+type app struct {
+	config    *internal.Config
+	notes     *internal.EntryService
+	nav       *internal.Navigator
+	json      bool
+	pick      bool
+	writeMode internal.WriteMode
+	resp      *response
+}
+
+func (a *app) load() error {
+	if a.config != nil {
+		return nil
+	}
+
+	config, err := internal.LoadConfig()
+	if err != nil {
+		return err
+	}
+	a.use(config)
+	return nil
+}
+
+// use sets up the services for config, the --dry-run and --read-only flags can only make a read-only config stricter
+func (a *app) use(config *internal.Config) {
+	a.config = config
+	a.notes = internal.NewNotesService(config)
+	if !a.notes.IsReadOnly() && a.writeMode != internal.WriteEnabled {
+		a.notes.SetWriteMode(a.writeMode)
+	}
+	a.nav = internal.NewNavigator(a.notes)
+
+	if a.pick {
+		a.notes.SetPicker(func(query string, candidates []string) (string, error) {
+			return internal.PickFromMenu(fmt.Sprintf("Which %s: ", query), candidates)
+		})
+	}
+}
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	flags   func(fs *flag.FlagSet)
+	run     func(a *app, cmd *command, args []string) (internal.Result, error)
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"init", "[root]", "Scaffold an indexed PARA garden with starter templates and write the config file", initFlags, runInit},
+		{"doctor", "", "Check the whole garden for indexing, naming, link and permission problems", doctorFlags, runDoctor},
+		{"ls", "[path]", "List the entries of a directory", lsFlags, runLs},
+		{"tree", "[path]", "Print the garden as a tree", treeFlags, runTree},
+		{"mkdir", "<path>", "Create a directory and any missing parents, respecting their indexing", nil, runMkdir},
+		{"new", "[dir]", "Create a note, in the inbox with the current date by default", newFlags, runNew},
+		{"open", "<path>", "Open a note in the editor", nil, runOpen},
+		{"pin", "[path]", "Bookmark an entry, or list the numbered bookmarks", nil, runPin},
+		{"unpin", "<path|n>", "Remove a bookmark", nil, runUnpin},
+		{"jump", "<n>", "Open a numbered bookmark, directories in a tmux session", nil, runJump},
+		{"recent", "", "List notes by how often and recently they were opened", recentFlags, runRecent},
+		{"search", "<query>", "Search the content of every note, printing path:line: snippet", searchFlags, runSearch},
+		{"tags", "", "List every frontmatter and inline #tag with the number of notes carrying it", nil, runTags},
+		{"tagged", "<tag>", "List the notes with a tag or a tag nested under it", nil, runTagged},
+		{"links", "<note|check>", "List the backlinks to a note and the links going out of it, or check every link", linksFlags, runLinks},
+		{"index-search", "", "Update the search index, reading only notes that changed", indexSearchFlags, runIndexSearch},
+		{"mv", "<path> <dir>", "Move an entry into another directory, reindexing both", nil, runMv},
+		{"rm", "<path>", "Delete an entry, closing the gap in its directory's indexing", rmFlags, runRm},
+		{"rename", "<path> <name>", "Rename an entry, keeping its index", nil, runRename},
+		{"reorder", "<path> <up|down|top|bottom|N>", "Move an entry within its indexed directory", nil, runReorder},
+		{"batch", "", "Apply mv, rename, reorder, archive and mkdir operations read from stdin, all or nothing", batchFlags, runBatch},
+		{"index", "<apply|remove|validate|repair> [path]", "Manage a directory's numeric indexing", nil, runIndex},
+		{"template", "<list|render> [name]", "List templates or render one to stdout", templateFlags, runTemplate},
+		{"archive", "<path>", "Move an entry into the archive directory", nil, runArchive},
+		{"daily", "", "Open the daily note, creating it from its template", periodicFlags, runPeriodic},
+		{"weekly", "", "Open the weekly note, creating it from its template", periodicFlags, runPeriodic},
+		{"monthly", "", "Open the monthly note, creating it from its template", periodicFlags, runPeriodic},
+		{"quarterly", "", "Open the quarterly note, creating it from its template", periodicFlags, runPeriodic},
+		{"yearly", "", "Open the yearly note, creating it from its template", periodicFlags, runPeriodic},
+		{"missing", "<period>", "List periods without a note in a range", missingFlags, runMissing},
+		{"capture", "[text]", "Append timestamped text to the capture note, reads stdin without text", captureFlags, runCapture},
+		{"append", "<note> [text]", "Insert text into a note under a heading, reads stdin without text", appendFlags, runAppend},
+		{"completion", "<bash|zsh|fish>", "Print a shell completion script", nil, runCompletion},
+		{"help", "[command]", "Show help for a command", nil, runHelp},
+		{"__complete", "[words]", "Print completion candidates for the last word", nil, runComplete},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
 }
 
 func printUsage() {
-	fmt.Println("Usage: garden-logger-cli <command> [args]")
+	fmt.Println("Usage: garden-logger-cli [-v] [--json] [--pick] [--dry-run] [--read-only] <command> [args]")
 	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  new          Create a new note in inbox with current date")
-	fmt.Println("  open <path>  Open note at specified path")
+	for _, cmd := range commands {
+		if cmd.hidden() {
+			continue
+		}
+		fmt.Printf("  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Println()
+	fmt.Println("Run 'garden-logger-cli help <command>' for a command's arguments and flags")
 }
 
-func handleCommand(args []string) error {
-	command := args[0]
-
-	switch command {
-	case "new":
-		return handleNewCommand()
-	case "open":
-		if len(args) < 2 {
-			return fmt.Errorf("open command requires a path argument")
-		}
-		return handleOpenCommand(args[1])
-	default:
-		return fmt.Errorf("unknown command: %s", command)
+func handleCommand(a *app, args []string) (internal.Result, error) {
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		return internal.Result{}, fmt.Errorf("%w: unknown command %s", internal.ErrUsage, args[0])
 	}
+
+	return cmd.run(a, cmd, args[1:])
 }
 
-func handleNewCommand() error {
-	config, err := internal.LoadConfig()
-	if err != nil {
-		return err
+func runHelp(a *app, cmd *command, args []string) (internal.Result, error) {
+	if len(args) == 0 {
+		printUsage()
+		return internal.Result{}, nil
+	}
+
+	target := findCommand(args[0])
+	if target == nil {
+		return internal.Result{}, fmt.Errorf("%w: unknown command %s", internal.ErrUsage, args[0])
 	}
+	return target.run(a, target, []string{"--help"})
+}
 
-	notes := internal.NewNotesService(config)
-	nav := internal.NewNavigator(notes)
+// hidden commands are left out of the usage and completion
+func (c *command) hidden() bool {
+	return strings.HasPrefix(c.name, "__")
+}
 
-	err = nav.NavigateTo("")
-	if err != nil {
-		return err
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: garden-logger-cli %s %s\n\n%s\n", c.name, c.usage, c.summary)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(out)
+			fmt.Fprintln(out, "Flags:")
+			fs.PrintDefaults()
+		}
+	}
+	if c.flags != nil {
+		c.flags(fs)
 	}
+	return fs
+}
+
+// flagString, flagBool and flagInt read back a flag the command's flags function declared
+func flagString(fs *flag.FlagSet, name string) string {
+	return fs.Lookup(name).Value.(flag.Getter).Get().(string)
+}
 
-	err = nav.NavigateTo(config.InboxDir)
+func flagBool(fs *flag.FlagSet, name string) bool {
+	return fs.Lookup(name).Value.(flag.Getter).Get().(bool)
+}
+
+func flagInt(fs *flag.FlagSet, name string) int {
+	return fs.Lookup(name).Value.(flag.Getter).Get().(int)
+}
+
+// parse reads the command's flags and checks it got at least minArgs positional arguments
+func (c *command) parse(fs *flag.FlagSet, args []string, minArgs int) ([]string, error) {
+	positional, err := parseInterspersed(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil, err
+	}
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("%w: %w", internal.ErrUsage, err)
+	}
+	if len(positional) < minArgs {
+		return nil, fmt.Errorf("%w: garden-logger-cli %s %s", internal.ErrUsage, c.name, c.usage)
 	}
+	return positional, nil
+}
 
-	filePath, err := notes.CreateEntryFromUserInput(nav.CurrentDirectory(), "", false)
-	if err != nil {
-		return err
+// parseInterspersed parses flags that may appear before, between or after positional arguments.
+// Anything after "--" is positional, as is text like "- item" that the flag package would reject.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(positional, args[i+1:]...), nil
+		}
+		if !isFlagArg(arg) {
+			positional = append(positional, arg)
+			continue
+		}
+
+		flagArgs := []string{arg}
+		name := strings.TrimLeft(arg, "-")
+		if !strings.Contains(name, "=") && !isBoolFlag(fs, name) && i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+		if err := fs.Parse(flagArgs); err != nil {
+			return nil, err
+		}
 	}
+	return positional, nil
+}
 
-	return notes.LaunchNoteEditor(filePath)
+func isFlagArg(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-' && arg[1] != ' '
 }
 
-func handleOpenCommand(path string) error {
-	config, err := internal.LoadConfig()
-	if err != nil {
-		return err
+func isBoolFlag(fs *flag.FlagSet, name string) bool {
+	f := fs.Lookup(name)
+	if f == nil {
+		return false
+	}
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+func readTextArg(positional []string) (string, error) {
+	if len(positional) > 0 {
+		return strings.Join(positional, " "), nil
 	}
 
-	notes := internal.NewNotesService(config)
-	return notes.LaunchNoteEditor(path)
-}
\ No newline at end of file
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return string(input), nil
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"garden-logger/internal"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+func newFlags(fs *flag.FlagSet) {
+	fs.String("name", "", "Name of the note, defaults to the current date")
+	fs.String("template", "", "Template to create the note from")
+	fs.String("on-exists", "", "What to do when the note already exists: open, suffix or abort (default from config)")
+	fs.Bool("no-open", false, "Print the note's path instead of opening it")
+}
+
+func runNew(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 0)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	name := flagString(fs, "name")
+	template := flagString(fs, "template")
+	onExists := flagString(fs, "on-exists")
+	noOpen := flagBool(fs, "no-open")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	if onExists != "" {
+		a.config.OnCollision, err = internal.ParseCollisionPolicy(onExists)
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+
+	dirPath := a.config.InboxDir
+	if len(positional) > 0 {
+		dirPath, err = a.notes.ResolveDir(positional[0])
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+
+	if err := a.nav.NavigateTo(dirPath); err != nil {
+		return internal.Result{}, err
+	}
+
+	var filePath string
+	if template != "" {
+		templatePath, err := a.notes.ResolveTemplate(template)
+		if err != nil {
+			return internal.Result{}, err
+		}
+		filePath, err = a.notes.CreateEntryFromTemplate(a.nav.CurrentDirectory(), name, templatePath)
+		if err != nil {
+			return internal.Result{}, err
+		}
+	} else {
+		filePath, err = a.notes.CreateEntryFromUserInput(a.nav.CurrentDirectory(), name, false)
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+
+	if noOpen {
+		a.notes.RecordVisit(filePath)
+		a.emitPath(filePath)
+		return internal.Result{Outcome: internal.OutcomeCreated, Path: filePath}, nil
+	}
+	return a.notes.LaunchNoteEditor(filePath)
+}
+
+func runOpen(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	filePath, err := a.notes.ResolveNote(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	return a.notes.LaunchNoteEditor(filePath)
+}
+
+func recentFlags(fs *flag.FlagSet) {
+	fs.Int("limit", 20, "Number of notes to list, 0 for all")
+}
+
+func runRecent(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	if _, err := cmd.parse(fs, args, 0); err != nil {
+		return internal.Result{}, err
+	}
+
+	limit := flagInt(fs, "limit")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	recent, err := a.notes.RecentNotes(limit)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitRecent(recent)
+	return internal.Result{}, nil
+}
+
+func searchFlags(fs *flag.FlagSet) {
+	fs.Bool("phrase", false, "Match the query as one phrase instead of every word")
+	fs.Bool("regex", false, "Treat the query as a regular expression")
+	fs.Bool("case", false, "Match letter case")
+	fs.Int("limit", 0, "Stop after this many matches, 0 for all")
+	fs.Bool("open", false, "Open the first match at its line, or pick one with --pick")
+}
+
+func runSearch(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	phrase := flagBool(fs, "phrase")
+	regex := flagBool(fs, "regex")
+	caseSensitive := flagBool(fs, "case")
+	limit := flagInt(fs, "limit")
+	open := flagBool(fs, "open")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	hits, err := a.notes.Search(strings.Join(positional, " "), internal.SearchOptions{
+		Phrase:        phrase,
+		Regex:         regex,
+		CaseSensitive: caseSensitive,
+		Limit:         limit,
+	})
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if !open {
+		a.emitHits(hits)
+		return internal.Result{}, nil
+	}
+	if len(hits) == 0 {
+		return internal.Result{}, fmt.Errorf("%w: no matches for %q", internal.ErrNotFound, strings.Join(positional, " "))
+	}
+
+	hit := hits[0]
+	if a.pick && len(hits) > 1 {
+		hit, err = pickHit(hits)
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+	return a.notes.LaunchNoteEditorAt(hit.Path, hit.Line)
+}
+
+func indexSearchFlags(fs *flag.FlagSet) {
+	fs.Bool("rebuild", false, "Throw the index away and read every note again")
+}
+
+func runIndexSearch(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	if _, err := cmd.parse(fs, args, 0); err != nil {
+		return internal.Result{}, err
+	}
+
+	rebuild := flagBool(fs, "rebuild")
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	_, stats, err := a.notes.UpdateSearchIndex(rebuild)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitIndexStats(stats)
+	if stats.Updated == 0 && stats.Removed == 0 {
+		return internal.Result{}, nil
+	}
+	return internal.Result{Outcome: internal.OutcomeDone}, nil
+}
+
+func pickHit(hits []internal.SearchHit) (internal.SearchHit, error) {
+	var items []string
+	for _, hit := range hits {
+		items = append(items, hit.String())
+	}
+
+	choice, err := internal.PickFromMenu("Open match: ", items)
+	if err != nil {
+		return internal.SearchHit{}, err
+	}
+	for _, hit := range hits {
+		if hit.String() == choice {
+			return hit, nil
+		}
+	}
+	return internal.SearchHit{}, fmt.Errorf("unknown match %q", choice)
+}
+
+func periodicFlags(fs *flag.FlagSet) {
+	fs.String("date", "today", "Date inside the period: today, yesterday, tomorrow, +N, -N or YYYY-MM-DD")
+	fs.Int("offset", 0, "Number of periods to move from the date")
+	fs.Bool("prev", false, "Open the previous period")
+	fs.Bool("next", false, "Open the next period")
+}
+
+func runPeriodic(a *app, cmd *command, args []string) (internal.Result, error) {
+	period, err := internal.ParsePeriod(cmd.name)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	fs := cmd.flagSet()
+	if _, err := cmd.parse(fs, args, 0); err != nil {
+		return internal.Result{}, err
+	}
+
+	dateArg := flagString(fs, "date")
+	offset := flagInt(fs, "offset")
+	prev := flagBool(fs, "prev")
+	next := flagBool(fs, "next")
+
+	date, err := internal.ParseDateArg(dateArg, time.Now())
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if prev {
+		offset--
+	}
+	if next {
+		offset++
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	filePath, err := a.notes.OpenPeriod(period, period.Add(date, offset))
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	return a.notes.LaunchNoteEditor(filePath)
+}
+
+func missingFlags(fs *flag.FlagSet) {
+	fs.String("from", time.Now().AddDate(0, -1, 0).Format("2006-01-02"), "First date of the range")
+	fs.String("to", "today", "Last date of the range")
+}
+
+func runMissing(a *app, cmd *command, args []string) (internal.Result, error) {
+	now := time.Now()
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	fromArg := flagString(fs, "from")
+	toArg := flagString(fs, "to")
+
+	period, err := internal.ParsePeriod(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	from, err := internal.ParseDateArg(fromArg, now)
+	if err != nil {
+		return internal.Result{}, err
+	}
+	to, err := internal.ParseDateArg(toArg, now)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	missing, err := a.notes.MissingPeriods(period, from, to)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitPeriods(missing)
+	return internal.Result{}, nil
+}
+
+func captureFlags(fs *flag.FlagSet) {
+	fs.String("heading", "", "Heading to capture under, created when missing")
+	fs.Bool("new", false, "Create a new inbox note instead of appending to the capture note")
+	fs.Bool("prompt", false, "Ask for the text through rofi")
+}
+
+func runCapture(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 0)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	heading := flagString(fs, "heading")
+	newNote := flagBool(fs, "new")
+	prompt := flagBool(fs, "prompt")
+
+	var text string
+	if prompt {
+		text, err = internal.PromptText("Capture: ")
+	} else {
+		text, err = readTextArg(positional)
+	}
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	filePath, err := a.notes.Capture(text, internal.CaptureOptions{Heading: heading, NewNote: newNote})
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	slog.Info("Captured text", "path", filePath)
+	return internal.Result{Outcome: internal.OutcomeDone, Path: filePath}, nil
+}
+
+func appendFlags(fs *flag.FlagSet) {
+	fs.String("heading", "", "Heading to insert under, created when missing")
+	fs.String("position", "bottom", "Where to insert in the section: top or bottom")
+	fs.Bool("timestamp", false, "Prefix the text with the current time")
+}
+
+func runAppend(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	heading := flagString(fs, "heading")
+	positionArg := flagString(fs, "position")
+	timestamp := flagBool(fs, "timestamp")
+
+	position, err := internal.ParseInsertPosition(positionArg)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	text, err := readTextArg(positional[1:])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	notePath, err := a.notes.ResolveNote(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	err = a.notes.AppendToNote(notePath, text, internal.AppendOptions{
+		Heading:   heading,
+		Position:  position,
+		Timestamp: timestamp,
+	})
+	if err != nil {
+		return internal.Result{}, err
+	}
+	return internal.Result{Outcome: internal.OutcomeDone, Path: notePath}, nil
+}
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"garden-logger/internal"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// jsonVersion is bumped whenever a field in the JSON output changes meaning or goes away
+const jsonVersion = 1
+
+// Stable error codes for --json output
+const (
+	codeNotFound      = "not_found"
+	codeAlreadyExists = "already_exists"
+	codeAmbiguous     = "ambiguous"
+	codeIndexConflict = "index_conflict"
+	codeValidation    = "validation"
+	codeConfig        = "config"
+	codeReadOnly      = "read_only"
+	codeUsage         = "usage"
+	codeError         = "error"
+)
+
+type response struct {
+	Version   int           `json:"version"`
+	Command   string        `json:"command"`
+	OK        bool          `json:"ok"`
+	Outcome   string        `json:"outcome,omitempty"`
+	DryRun    bool          `json:"dryRun,omitempty"`
+	Path      string        `json:"path,omitempty"`
+	Launched  bool          `json:"launched,omitempty"`
+	Entries   []entryJSON   `json:"entries,omitempty"`
+	Tree      *treeJSON     `json:"tree,omitempty"`
+	Periods   []periodJSON  `json:"periods,omitempty"`
+	Valid     *bool         `json:"valid,omitempty"`
+	Content   string        `json:"content,omitempty"`
+	Findings  []findingJSON `json:"findings,omitempty"`
+	Hits      []hitJSON     `json:"hits,omitempty"`
+	Recent    []recentJSON  `json:"recent,omitempty"`
+	Pins      []pinJSON     `json:"pins,omitempty"`
+	Tags      []tagJSON     `json:"tags,omitempty"`
+	Notes     []string      `json:"notes,omitempty"`
+	Backlinks []linkJSON    `json:"backlinks,omitempty"`
+	Outgoing  []linkJSON    `json:"outgoing,omitempty"`
+	Broken    []linkJSON    `json:"broken,omitempty"`
+	Index     *indexJSON    `json:"index,omitempty"`
+	Changes   []changeJSON  `json:"changes,omitempty"`
+	Error     *errorJSON    `json:"error,omitempty"`
+}
+
+type entryJSON struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Ext   string `json:"ext"`
+	IsDir bool   `json:"isDir"`
+	Path  string `json:"path"`
+	// Meta is only filled in when asked for, reading frontmatter means opening every note
+	Meta internal.Metadata `json:"meta,omitempty"`
+}
+
+type treeJSON struct {
+	entryJSON
+	Notes    int         `json:"notes,omitempty"`
+	Indexed  bool        `json:"indexed,omitempty"`
+	Children []*treeJSON `json:"children,omitempty"`
+}
+
+type periodJSON struct {
+	Start string `json:"start"`
+	Path  string `json:"path"`
+}
+
+type findingJSON struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix"`
+	Fixable  bool   `json:"fixable"`
+	Fixed    bool   `json:"fixed"`
+}
+
+type hitJSON struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Snippet string `json:"snippet"`
+}
+
+type recentJSON struct {
+	Path      string  `json:"path"`
+	Visits    int     `json:"visits"`
+	LastVisit string  `json:"lastVisit"`
+	Score     float64 `json:"score"`
+}
+
+type pinJSON struct {
+	Number  int    `json:"number"`
+	Path    string `json:"path"`
+	IsDir   bool   `json:"isDir"`
+	Missing bool   `json:"missing"`
+}
+
+type tagJSON struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type linkJSON struct {
+	Source   string `json:"source"`
+	Line     int    `json:"line"`
+	Kind     string `json:"kind"`
+	Target   string `json:"target"`
+	Heading  string `json:"heading,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Raw      string `json:"raw"`
+	Path     string `json:"path,omitempty"`
+	Resolved bool   `json:"resolved"`
+	Problem  string `json:"problem,omitempty"`
+	Fix      string `json:"fix,omitempty"`
+	Fixed    bool   `json:"fixed,omitempty"`
+}
+
+type indexJSON struct {
+	Notes   int `json:"notes"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+	Terms   int `json:"terms"`
+}
+
+type changeJSON struct {
+	Op      string `json:"op"`
+	Path    string `json:"path"`
+	NewPath string `json:"newPath,omitempty"`
+}
+
+type errorJSON struct {
+	Code       string   `json:"code"`
+	Message    string   `json:"message"`
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+func (a *app) relPath(absPath string) string {
+	if a.config == nil {
+		return absPath
+	}
+	relPath, err := filepath.Rel(a.config.RootDir, absPath)
+	if err != nil {
+		return absPath
+	}
+	return relPath
+}
+
+func (a *app) entryJSON(entry *internal.Entry) entryJSON {
+	return entryJSON{
+		Index: entry.EntryIndex,
+		Name:  entry.Name,
+		Ext:   entry.Ext,
+		IsDir: entry.IsDir,
+		Path:  a.relPath(entry.FilePath()),
+	}
+}
+
+// emitPath reports the path an operation produced
+func (a *app) emitPath(path string) {
+	if a.json {
+		a.resp.Path = path
+		return
+	}
+	fmt.Println(path)
+}
+
+// emitMessage prints a human readable status line, JSON output relies on the recorded changes instead
+func (a *app) emitMessage(format string, args ...any) {
+	if a.json {
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+func (a *app) emitEntries(entries []*internal.Entry, long bool, meta bool) {
+	if a.json {
+		a.resp.Entries = []entryJSON{}
+		for _, entry := range entries {
+			entryJSON := a.entryJSON(entry)
+			if meta && len(entry.Metadata()) > 0 {
+				entryJSON.Meta = entry.Metadata()
+			}
+			a.resp.Entries = append(a.resp.Entries, entryJSON)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.String()
+		if entry.IsDir {
+			name += "/"
+		}
+		if meta {
+			name += metadataSummary(entry.Metadata())
+		}
+
+		if !long {
+			fmt.Println(name)
+			continue
+		}
+
+		kind := "file"
+		if entry.IsDir {
+			kind = "dir"
+		}
+		fmt.Printf("%3d\t%s\t%s\n", entry.EntryIndex, kind, name)
+	}
+}
+
+// metadataSummary shows the status and tags of a note after its name
+func metadataSummary(meta internal.Metadata) string {
+	var parts []string
+	if status := meta.Status(); status != "" {
+		parts = append(parts, "["+status+"]")
+	}
+	for _, tag := range meta.Tags() {
+		parts = append(parts, "#"+tag)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "  " + strings.Join(parts, " ")
+}
+
+func (a *app) emitTree(tree *internal.TreeNode, style treeStyle) {
+	if a.json {
+		a.resp.Tree = a.treeJSON(tree)
+		return
+	}
+
+	if style.markdown {
+		style.printMarkdown([]*internal.TreeNode{tree}, "")
+		return
+	}
+	fmt.Printf("%s [%s]\n", style.name(tree), style.summary(tree))
+	style.print(tree.Children, "")
+}
+
+func (a *app) treeJSON(node *internal.TreeNode) *treeJSON {
+	tree := &treeJSON{entryJSON: a.entryJSON(node.Entry), Notes: node.Notes, Indexed: node.IsIndexed}
+	tree.Path = node.Path
+	for _, child := range node.Children {
+		tree.Children = append(tree.Children, a.treeJSON(child))
+	}
+	return tree
+}
+
+func (a *app) emitPeriods(missing []internal.MissingPeriod) {
+	if a.json {
+		a.resp.Periods = []periodJSON{}
+	}
+	for _, m := range missing {
+		start := m.Start.Format(time.DateOnly)
+		if a.json {
+			a.resp.Periods = append(a.resp.Periods, periodJSON{Start: start, Path: m.Path})
+			continue
+		}
+		fmt.Printf("%s\t%s\n", start, m.Path)
+	}
+}
+
+func (a *app) emitFindings(findings []internal.Finding, fixed []bool) {
+	if a.json {
+		a.resp.Findings = []findingJSON{}
+	}
+	for i, finding := range findings {
+		if a.json {
+			a.resp.Findings = append(a.resp.Findings, findingJSON{
+				Severity: finding.Severity.String(),
+				Kind:     finding.Kind,
+				Path:     finding.Path,
+				Message:  finding.Message,
+				Fix:      finding.Fix,
+				Fixable:  finding.Fixable(),
+				Fixed:    fixed[i],
+			})
+			continue
+		}
+
+		status := ""
+		if fixed[i] {
+			status = " (fixed)"
+		} else if finding.Fixable() {
+			status = " (--fix)"
+		}
+		fmt.Printf("%-7s  %s: %s\n         fix: %s%s\n", finding.Severity, displayPath(finding.Path), finding.Message, finding.Fix, status)
+	}
+}
+
+func (a *app) emitHits(hits []internal.SearchHit) {
+	if a.json {
+		a.resp.Hits = []hitJSON{}
+	}
+	for _, hit := range hits {
+		if a.json {
+			a.resp.Hits = append(a.resp.Hits, hitJSON{Path: hit.Path, Line: hit.Line, Snippet: hit.Snippet})
+			continue
+		}
+		fmt.Println(hit)
+	}
+}
+
+func (a *app) emitRecent(recent []internal.RecentNote) {
+	if a.json {
+		a.resp.Recent = []recentJSON{}
+	}
+	for _, note := range recent {
+		if a.json {
+			a.resp.Recent = append(a.resp.Recent, recentJSON{
+				Path:      note.Path,
+				Visits:    note.Visit.Count,
+				LastVisit: note.Visit.Last.Format(time.RFC3339),
+				Score:     note.Score,
+			})
+			continue
+		}
+		fmt.Println(note.Path)
+	}
+}
+
+func (a *app) emitPins(pins []internal.Pin) {
+	if a.json {
+		a.resp.Pins = []pinJSON{}
+	}
+	for _, pin := range pins {
+		if a.json {
+			path := pin.Path
+			if path == "" {
+				path = pin.Key
+			}
+			a.resp.Pins = append(a.resp.Pins, pinJSON{Number: pin.Number, Path: path, IsDir: pin.IsDir, Missing: pin.Path == ""})
+			continue
+		}
+
+		switch {
+		case pin.Path == "":
+			fmt.Printf("%d\t%s (missing)\n", pin.Number, pin.Key)
+		case pin.IsDir:
+			fmt.Printf("%d\t%s/\n", pin.Number, pin.Path)
+		default:
+			fmt.Printf("%d\t%s\n", pin.Number, pin.Path)
+		}
+	}
+}
+
+func (a *app) emitTags(tags []internal.TagCount) {
+	if a.json {
+		a.resp.Tags = []tagJSON{}
+	}
+	for _, tag := range tags {
+		if a.json {
+			a.resp.Tags = append(a.resp.Tags, tagJSON{Tag: tag.Tag, Count: tag.Count})
+			continue
+		}
+		fmt.Printf("%d\t#%s\n", tag.Count, tag.Tag)
+	}
+}
+
+// emitNotes lists note paths, one per line
+func (a *app) emitNotes(notePaths []string) {
+	if a.json {
+		a.resp.Notes = append([]string{}, notePaths...)
+		return
+	}
+	for _, notePath := range notePaths {
+		fmt.Println(notePath)
+	}
+}
+
+func noteLinkJSON(link internal.NoteLink) linkJSON {
+	return linkJSON{
+		Source:   link.Source,
+		Line:     link.Line,
+		Kind:     string(link.Kind),
+		Target:   link.Target,
+		Heading:  link.Heading,
+		Text:     link.Text,
+		Raw:      link.Raw,
+		Path:     link.Path,
+		Resolved: link.Path != "",
+	}
+}
+
+func (a *app) linksJSON(links []internal.NoteLink) []linkJSON {
+	result := []linkJSON{}
+	for _, link := range links {
+		result = append(result, noteLinkJSON(link))
+	}
+	return result
+}
+
+func (a *app) emitLinks(backlinks []internal.NoteLink, outgoing []internal.NoteLink) {
+	if a.json {
+		a.resp.Backlinks = a.linksJSON(backlinks)
+		a.resp.Outgoing = a.linksJSON(outgoing)
+		return
+	}
+
+	fmt.Printf("Backlinks (%d)\n", len(backlinks))
+	for _, link := range backlinks {
+		fmt.Printf("  %s:%d: %s\n", link.Source, link.Line, link.Raw)
+	}
+	fmt.Printf("Outgoing (%d)\n", len(outgoing))
+	for _, link := range outgoing {
+		target := link.Path
+		if target == "" {
+			target = "(missing)"
+		}
+		fmt.Printf("  %d: %s -> %s\n", link.Line, link.Raw, target)
+	}
+}
+
+// emitBrokenLinks reports the links CheckLinks found, stale ones count as fixed after a --fix
+func (a *app) emitBrokenLinks(broken []internal.BrokenLink, fixed bool) {
+	if a.json {
+		a.resp.Broken = []linkJSON{}
+	}
+	for _, link := range broken {
+		stale := link.Problem == internal.LinkStale
+		if a.json {
+			linkJSON := noteLinkJSON(link.NoteLink)
+			// Stale links name the note they meant but don't resolve as written
+			linkJSON.Resolved = false
+			linkJSON.Problem = string(link.Problem)
+			linkJSON.Fix = link.Fix
+			linkJSON.Fixed = fixed && stale
+			a.resp.Broken = append(a.resp.Broken, linkJSON)
+			continue
+		}
+
+		if !stale {
+			fmt.Printf("missing  %s:%d: %s doesn't match anything\n", link.Source, link.Line, link.Raw)
+			continue
+		}
+		status := " (--fix)"
+		if fixed {
+			status = " (fixed)"
+		}
+		fmt.Printf("stale    %s:%d: %s now points to %s\n         fix: %s%s\n", link.Source, link.Line, link.Raw, link.Path, link.Fix, status)
+	}
+}
+
+func (a *app) emitIndexStats(stats internal.IndexStats) {
+	if a.json {
+		a.resp.Index = &indexJSON{Notes: stats.Notes, Updated: stats.Updated, Removed: stats.Removed, Terms: stats.Terms}
+		return
+	}
+	fmt.Printf("Indexed %d notes, %d updated, %d removed, %d words\n", stats.Notes, stats.Updated, stats.Removed, stats.Terms)
+}
+
+func (a *app) emitValid(valid bool, message string) {
+	if a.json {
+		a.resp.Valid = &valid
+		return
+	}
+	fmt.Println(message)
+}
+
+func (a *app) emitContent(content string) {
+	if a.json {
+		a.resp.Content = content
+		return
+	}
+	fmt.Print(content)
+}
+
+// finish writes the JSON response for the command, text output has already been printed by then
+func (a *app) finish(result internal.Result, err error) {
+	if !a.json {
+		if a.isDryRun() {
+			a.printPlannedChanges()
+		}
+		return
+	}
+
+	a.resp.Version = jsonVersion
+	a.resp.DryRun = a.isDryRun()
+	a.resp.OK = err == nil
+
+	if err == nil {
+		a.resp.Outcome = result.Outcome.String()
+		a.resp.Launched = result.Outcome == internal.OutcomeLaunched
+		if result.Path != "" {
+			a.resp.Path = result.Path
+		}
+	} else {
+		a.resp.Error = &errorJSON{Code: errorCode(err), Message: err.Error()}
+
+		var ambiguousErr *internal.AmbiguousPathError
+		if errors.As(err, &ambiguousErr) {
+			a.resp.Error.Candidates = ambiguousErr.Candidates
+		}
+	}
+
+	for _, change := range a.takeChanges() {
+		a.resp.Changes = append(a.resp.Changes, changeJSON{
+			Op:      change.Op,
+			Path:    a.relPath(change.Path),
+			NewPath: a.relPath(change.NewPath),
+		})
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(a.resp)
+}
+
+// isDryRun falls back to the flags when the command failed before loading the config
+func (a *app) isDryRun() bool {
+	if a.notes == nil {
+		return a.writeMode == internal.WriteDryRun
+	}
+	return a.notes.IsDryRun()
+}
+
+func (a *app) takeChanges() []internal.Change {
+	if a.notes == nil {
+		return nil
+	}
+	return a.notes.TakeChanges()
+}
+
+// printPlannedChanges lists what a dry run would have done
+func (a *app) printPlannedChanges() {
+	for _, change := range a.takeChanges() {
+		if change.NewPath != "" {
+			fmt.Printf("would %s %s -> %s\n", change.Op, a.relPath(change.Path), a.relPath(change.NewPath))
+		} else {
+			fmt.Printf("would %s %s\n", change.Op, a.relPath(change.Path))
+		}
+	}
+}
+
+func errorCode(err error) string {
+	switch internal.ErrorExitCode(err) {
+	case internal.ExitAmbiguous:
+		return codeAmbiguous
+	case internal.ExitUsage:
+		return codeUsage
+	case internal.ExitConfig:
+		return codeConfig
+	case internal.ExitReadOnly:
+		return codeReadOnly
+	case internal.ExitNotFound:
+		return codeNotFound
+	case internal.ExitAlreadyExists:
+		return codeAlreadyExists
+	case internal.ExitIndexConflict:
+		return codeIndexConflict
+	case internal.ExitValidation:
+		return codeValidation
+	default:
+		return codeError
+	}
+}
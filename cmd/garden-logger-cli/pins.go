@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"garden-logger/internal"
+	"slices"
+	"strconv"
+)
+
+func runPin(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 0)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	if len(positional) == 0 {
+		pins, err := a.notes.Pins()
+		if err != nil {
+			return internal.Result{}, err
+		}
+		a.emitPins(pins)
+		return internal.Result{}, nil
+	}
+
+	entryPath, err := a.notes.ResolvePath(positional[0])
+	if err != nil {
+		return internal.Result{}, err
+	}
+	number, err := a.notes.PinEntry(entryPath)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitMessage("Pinned %s as %d", entryPath, number)
+	return internal.Result{Outcome: internal.OutcomeDone, Path: entryPath}, nil
+}
+
+func runUnpin(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	// A number unpins that bookmark even when its entry is gone
+	entryPath := positional[0]
+	if number, err := strconv.Atoi(positional[0]); err == nil {
+		pins, err := a.notes.Pins()
+		if err != nil {
+			return internal.Result{}, err
+		}
+		i := slices.IndexFunc(pins, func(pin internal.Pin) bool { return pin.Number == number })
+		if i < 0 {
+			return internal.Result{}, fmt.Errorf("bookmark %w: %d", internal.ErrNotFound, number)
+		}
+		entryPath = pins[i].Key
+	} else if !a.notes.IsPinned(entryPath) {
+		entryPath, err = a.notes.ResolvePath(entryPath)
+		if err != nil {
+			return internal.Result{}, err
+		}
+	}
+
+	if err := a.notes.UnpinEntry(entryPath); err != nil {
+		return internal.Result{}, err
+	}
+	a.emitMessage("Unpinned %s", entryPath)
+	return internal.Result{Outcome: internal.OutcomeDone}, nil
+}
+
+func runJump(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	number, err := strconv.Atoi(positional[0])
+	if err != nil {
+		return internal.Result{}, fmt.Errorf("%w: bookmark number must be a number, got %q", internal.ErrUsage, positional[0])
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	pin, err := a.notes.GetPin(number)
+	if err != nil {
+		return internal.Result{}, err
+	}
+	if pin.IsDir {
+		return a.notes.LaunchDirectoryEditor(pin.Path)
+	}
+	return a.notes.LaunchNoteEditor(pin.Path)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"garden-logger/internal"
+	"strings"
+)
+
+func runTags(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	if _, err := cmd.parse(fs, args, 0); err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	tags, err := a.notes.Tags()
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitTags(tags)
+	return internal.Result{}, nil
+}
+
+func runTagged(a *app, cmd *command, args []string) (internal.Result, error) {
+	fs := cmd.flagSet()
+	positional, err := cmd.parse(fs, args, 1)
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	if err := a.load(); err != nil {
+		return internal.Result{}, err
+	}
+
+	notePaths, err := a.notes.TaggedNotes(strings.Join(positional, " "))
+	if err != nil {
+		return internal.Result{}, err
+	}
+
+	a.emitNotes(notePaths)
+	return internal.Result{}, nil
+}
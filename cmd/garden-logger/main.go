@@ -1,7 +1,6 @@
 package main
 
 import (
-	"errors"
 	"garden-logger/internal"
 	"log/slog"
 	"os"
@@ -10,14 +9,11 @@ import (
 func main() {
 	slog.Info("Garden Logger main entry point")
 
-	if err := internal.StartApp(); err != nil {
-		var launchErr internal.LaunchSuccessError
-		if errors.As(err, &launchErr) {
-			os.Exit(0) // Success - program launched editor
-		}
+	result, err := internal.StartApp()
+	if err != nil {
 		slog.Error("Application Error", "error", err)
-		os.Exit(1)
+	} else {
+		slog.Info("Garden Logger completed successfully", "outcome", result.Outcome)
 	}
-
-	slog.Info("Garden Logger completed successfully")
+	os.Exit(internal.ExitCode(result, err))
 }
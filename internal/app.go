@@ -5,21 +5,22 @@ import (
 	"log/slog"
 )
 
-func StartApp() error {
-	var verbose bool
+func StartApp() (Result, error) {
+	var verbose, readOnly bool
 
 	flag.BoolVar(&verbose, "v", false, "Enable verbose logging")
+	flag.BoolVar(&readOnly, "read-only", false, "Browse without offering anything that changes the garden")
 	flag.Parse()
 
 	InitLogger(verbose)
 
-	slog.Info("Application startup initiated", "verbose", verbose)
+	slog.Info("Application startup initiated", "verbose", verbose, "readOnly", readOnly)
 
-	err := Browse()
+	result, err := Browse(readOnly)
 	if err != nil {
-		return err
+		return Result{}, err
 	}
 
-	slog.Info("Application startup completed successfully")
-	return nil
+	slog.Info("Application startup completed successfully", "outcome", result.Outcome)
+	return result, nil
 }
@@ -0,0 +1,378 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// BatchOp is one operation read by ParseBatch, paths are relative to the root and may leave out indexes
+type BatchOp struct {
+	Op       string `json:"op"`
+	Path     string `json:"path"`
+	Dest     string `json:"dest,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Position string `json:"position,omitempty"`
+	Line     int    `json:"-"`
+}
+
+// Batch operations and the arguments they take in the line format
+var batchArgs = map[string][]string{
+	"mkdir":   {"path"},
+	"mv":      {"path", "dest"},
+	"rename":  {"path", "name"},
+	"reorder": {"path", "position"},
+	"archive": {"path"},
+}
+
+// ParseBatch reads one operation per line, either as words like `mv "Inbox/Idea.md" Projects` or as a JSON object
+// like {"op": "mv", "path": "Inbox/Idea.md", "dest": "Projects"}. Blank lines and lines starting with # are skipped.
+func ParseBatch(r io.Reader) ([]BatchOp, error) {
+	var ops []BatchOp
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var op BatchOp
+		if strings.HasPrefix(text, "{") {
+			if err := json.Unmarshal([]byte(text), &op); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrUsage, line, err)
+			}
+		} else {
+			words, err := splitWords(text)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrUsage, line, err)
+			}
+			op = batchOpFromWords(words)
+		}
+		op.Line = line
+
+		if err := op.check(); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrUsage, line, err)
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+
+	return ops, nil
+}
+
+func batchOpFromWords(words []string) BatchOp {
+	op := BatchOp{Op: words[0]}
+	args := words[1:]
+	for i, arg := range batchArgs[op.Op] {
+		if i >= len(args) {
+			break
+		}
+		value := args[i]
+		if i == len(batchArgs[op.Op])-1 {
+			value = strings.Join(args[i:], " ")
+		}
+		op.set(arg, value)
+	}
+	return op
+}
+
+func (op *BatchOp) set(arg string, value string) {
+	switch arg {
+	case "path":
+		op.Path = value
+	case "dest":
+		op.Dest = value
+	case "name":
+		op.Name = value
+	case "position":
+		op.Position = value
+	}
+}
+
+func (op *BatchOp) get(arg string) string {
+	switch arg {
+	case "path":
+		return op.Path
+	case "dest":
+		return op.Dest
+	case "name":
+		return op.Name
+	case "position":
+		return op.Position
+	default:
+		return ""
+	}
+}
+
+// check makes sure the operation exists and has every argument it needs
+func (op *BatchOp) check() error {
+	args, ok := batchArgs[op.Op]
+	if !ok {
+		return fmt.Errorf("unknown operation %q (expected mkdir, mv, rename, reorder or archive)", op.Op)
+	}
+	for _, arg := range args {
+		if op.get(arg) == "" {
+			return fmt.Errorf("%s needs %s", op.Op, strings.Join(args, " and "))
+		}
+	}
+	return nil
+}
+
+// splitWords splits a line on spaces, keeping quoted parts and backslash escaped characters together
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && quote != '\'' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// batchPlan is the garden's shape as index-free paths, updated as each operation is validated
+type batchPlan struct {
+	entries map[string]bool
+	indexed map[string]bool
+}
+
+func (s *EntryService) newBatchPlan() (*batchPlan, error) {
+	plan := &batchPlan{entries: map[string]bool{}, indexed: map[string]bool{}}
+	plan.indexed[""] = s.files.isIndexed(s.config.RootDir)
+
+	err := s.WalkGarden(func(entryPath string, entry *Entry) error {
+		key := StripIndexes(entryPath)
+		plan.entries[key] = entry.IsDir
+		if entry.IsDir {
+			plan.indexed[key] = s.files.isIndexed(filepath.Join(s.config.RootDir, entryPath))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func planKey(entryPath string) string {
+	key := StripIndexes(filepath.Clean(entryPath))
+	if key == "." {
+		return ""
+	}
+	return key
+}
+
+func (p *batchPlan) isDir(key string) bool {
+	isDir, ok := p.entries[key]
+	return key == "" || (ok && isDir)
+}
+
+func (p *batchPlan) mkdir(key string) error {
+	if key == "" {
+		return nil
+	}
+	if err := p.mkdir(planKey(filepath.Dir(key))); err != nil {
+		return err
+	}
+	if isDir, ok := p.entries[key]; ok && !isDir {
+		return fmt.Errorf("%w: %s is a note", ErrEntryExists, key)
+	}
+	p.entries[key] = true
+	return nil
+}
+
+// rename moves key and everything below it to newKey
+func (p *batchPlan) rename(key string, newKey string) {
+	for _, m := range []map[string]bool{p.entries, p.indexed} {
+		var moved []string
+		for existing := range m {
+			if existing == key || strings.HasPrefix(existing, key+string(filepath.Separator)) {
+				moved = append(moved, existing)
+			}
+		}
+		for _, existing := range moved {
+			value := m[existing]
+			delete(m, existing)
+			m[newKey+strings.TrimPrefix(existing, key)] = value
+		}
+	}
+}
+
+func (p *batchPlan) move(key string, destKey string) error {
+	if _, ok := p.entries[key]; !ok {
+		return fmt.Errorf("entry %w: %s", ErrNotFound, key)
+	}
+	if !p.isDir(destKey) {
+		return fmt.Errorf("directory %w: %s", ErrNotFound, destKey)
+	}
+	if destKey == key || strings.HasPrefix(destKey, key+string(filepath.Separator)) {
+		return fmt.Errorf("%w: cannot move %s into itself", ErrValidation, key)
+	}
+
+	newKey := filepath.Join(destKey, filepath.Base(key))
+	if newKey == key {
+		return nil
+	}
+	if _, ok := p.entries[newKey]; ok {
+		return fmt.Errorf("%w: %s", ErrEntryExists, newKey)
+	}
+	p.rename(key, newKey)
+	return nil
+}
+
+// apply validates op against the plan and updates the plan with its effect
+func (p *batchPlan) apply(op BatchOp, archiveKey string) error {
+	key := planKey(op.Path)
+	if op.Op != "mkdir" && key == "" {
+		return fmt.Errorf("%w: the root directory is not an entry", ErrValidation)
+	}
+
+	switch op.Op {
+	case "mkdir":
+		return p.mkdir(key)
+	case "mv":
+		return p.move(key, planKey(op.Dest))
+	case "archive":
+		if err := p.mkdir(archiveKey); err != nil {
+			return err
+		}
+		return p.move(key, archiveKey)
+	case "rename":
+		isDir, ok := p.entries[key]
+		if !ok {
+			return fmt.Errorf("entry %w: %s", ErrNotFound, key)
+		}
+		if err := validateEntryName(op.Name); err != nil {
+			return err
+		}
+
+		newName := op.Name
+		if !isDir {
+			newName += filepath.Ext(key)
+		}
+		newKey := filepath.Join(filepath.Dir(key), newName)
+		if newKey == key {
+			return nil
+		}
+		if _, ok := p.entries[newKey]; ok {
+			return fmt.Errorf("%w: %s", ErrEntryExists, newKey)
+		}
+		p.rename(key, newKey)
+		return nil
+	case "reorder":
+		if _, ok := p.entries[key]; !ok {
+			return fmt.Errorf("entry %w: %s", ErrNotFound, key)
+		}
+		if !p.indexed[planKey(filepath.Dir(key))] {
+			return fmt.Errorf("%w: cannot reorder %s: directory is not indexed", ErrIndexConflict, key)
+		}
+		_, err := ParseReorderPosition(op.Position)
+		return err
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrUsage, op.Op)
+	}
+}
+
+// PlanBatch checks every operation against an in-memory plan of the garden without touching the disk
+func (s *EntryService) PlanBatch(ops []BatchOp) error {
+	plan, err := s.newBatchPlan()
+	if err != nil {
+		return err
+	}
+
+	archiveKey := planKey(s.config.ArchiveDir)
+	for _, op := range ops {
+		if err := plan.apply(op, archiveKey); err != nil {
+			return fmt.Errorf("line %d: %s: %w", op.Line, op.Op, err)
+		}
+	}
+	return nil
+}
+
+// ApplyBatch validates every operation up front, then applies them in order.
+// If one fails, everything already applied is rolled back.
+func (s *EntryService) ApplyBatch(ops []BatchOp) error {
+	if err := s.PlanBatch(ops); err != nil {
+		return err
+	}
+
+	tx := s.files.BeginTransaction()
+	for _, op := range ops {
+		slog.Debug("Applying batch operation", "line", op.Line, "op", op.Op, "path", op.Path)
+		if err := s.applyBatchOp(op); err != nil {
+			err = fmt.Errorf("line %d: %s: %w", op.Line, op.Op, err)
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				return errors.Join(err, fmt.Errorf("rollback failed: %w", rollbackErr))
+			}
+			return fmt.Errorf("%w, rolled back", err)
+		}
+	}
+	tx.Commit()
+	return nil
+}
+
+func (s *EntryService) applyBatchOp(op BatchOp) error {
+	switch op.Op {
+	case "mkdir":
+		_, err := s.EnsureDirectory(op.Path)
+		return err
+	case "mv":
+		_, err := s.MoveEntry(op.Path, op.Dest)
+		return err
+	case "archive":
+		_, err := s.ArchiveEntry(op.Path)
+		return err
+	case "rename":
+		_, entry, err := s.LoadEntryAt(op.Path)
+		if err != nil {
+			return err
+		}
+		return entry.Rename(op.Name)
+	case "reorder":
+		dir, entry, err := s.LoadEntryAt(op.Path)
+		if err != nil {
+			return err
+		}
+		return dir.Reorder(entry, op.Position)
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrUsage, op.Op)
+	}
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type CaptureOptions struct {
+	// Heading overrides the configured section to capture under, "" uses the config
+	Heading string
+	// NewNote creates a fresh inbox note instead of appending to the capture note
+	NewNote bool
+}
+
+// FormatCapture renders captured text as a timestamped bullet or block
+func FormatCapture(text string, format string, t time.Time) string {
+	timestamp := t.Format("2006-01-02 15:04")
+	text = strings.TrimSpace(text)
+
+	if format == "block" {
+		return fmt.Sprintf("**%s**\n\n%s\n", timestamp, text)
+	}
+
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		lines[i] = "  " + lines[i]
+	}
+	return fmt.Sprintf("- %s %s\n", timestamp, strings.Join(lines, "\n"))
+}
+
+// Capture appends text to the capture note, or writes it to a new inbox note, and returns the note's path
+func (s *EntryService) Capture(text string, opts CaptureOptions) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%w: nothing to capture", ErrValidation)
+	}
+
+	now := time.Now()
+	block := FormatCapture(text, s.config.Capture.Format, now)
+
+	heading := opts.Heading
+	if heading == "" {
+		heading = s.config.Capture.Heading
+	}
+
+	if opts.NewNote {
+		inbox, err := s.LoadDirectory(s.config.InboxDir)
+		if err != nil {
+			return "", err
+		}
+
+		name := now.Format("2006-01-02")
+		content := func(name string) string {
+			if heading != "" {
+				return InsertUnderHeading(titleHeading(name), heading, block, PositionBottom)
+			}
+			return AppendBlock(titleHeading(name), block)
+		}
+
+		entry := &Entry{
+			Name:       name,
+			EntryIndex: inbox.NewFileIndex(),
+			Ext:        ".md",
+		}
+		return s.createEntry(inbox, entry, content, CollisionSuffix)
+	}
+
+	notePath, err := s.EnsureNote(PeriodicConfig{Path: s.config.Capture.Path}, now)
+	if err != nil {
+		return "", err
+	}
+
+	slog.Debug("Capturing text", "path", notePath, "heading", heading)
+	err = s.AppendToNote(notePath, block, AppendOptions{Heading: heading, Position: PositionBottom})
+	if err != nil {
+		return "", err
+	}
+
+	return notePath, nil
+}
+
+type AppendOptions struct {
+	Heading   string
+	Position  InsertPosition
+	Timestamp bool
+}
+
+// AppendToNote inserts text into an existing note, under a heading when one is given
+func (s *EntryService) AppendToNote(notePath string, text string, opts AppendOptions) error {
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("%w: nothing to append", ErrValidation)
+	}
+
+	absPath := filepath.Join(s.config.RootDir, notePath)
+	existing, err := s.files.readFile(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to read note %s: %w", absPath, err)
+	}
+
+	block := strings.Trim(text, "\n")
+	if opts.Timestamp {
+		block = prefixTimestamp(block, time.Now())
+	}
+
+	var content string
+	if opts.Heading != "" {
+		content = InsertUnderHeading(string(existing), opts.Heading, block, opts.Position)
+	} else {
+		content = InsertBlock(string(existing), block, opts.Position)
+	}
+
+	slog.Debug("Appending to note", "path", notePath, "heading", opts.Heading, "position", opts.Position)
+	return s.files.writeAtomic(absPath, []byte(content))
+}
+
+// prefixTimestamp puts the current time in front of the first line, after any list marker
+func prefixTimestamp(text string, t time.Time) string {
+	timestamp := t.Format("2006-01-02 15:04")
+	for _, marker := range []string{"- ", "* ", "+ "} {
+		if strings.HasPrefix(text, marker) {
+			return marker + timestamp + " " + strings.TrimPrefix(text, marker)
+		}
+	}
+	return timestamp + " " + text
+}
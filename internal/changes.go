@@ -0,0 +1,552 @@
+package internal
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// Change describes one filesystem mutation, paths are absolute
+type Change struct {
+	Op      string `json:"op"`
+	Path    string `json:"path"`
+	NewPath string `json:"newPath,omitempty"`
+}
+
+const (
+	ChangeRename = "rename"
+	ChangeCreate = "create"
+	ChangeMkdir  = "mkdir"
+	ChangeWrite  = "write"
+	ChangeDelete = "delete"
+)
+
+// WriteMode decides whether a FileWriter touches the disk
+type WriteMode int
+
+const (
+	WriteEnabled WriteMode = iota
+	// WriteDryRun records changes without making them
+	WriteDryRun
+	// WriteReadOnly refuses every change
+	WriteReadOnly
+)
+
+// FileWriter makes every change to the garden so callers can report what an operation did, preview it in a
+// dry run or refuse it in read-only mode. Each EntryService has its own, shared with the directories it loads.
+// A nil FileWriter reads the disk as it is.
+type FileWriter struct {
+	mode    WriteMode
+	changes []Change
+	planned *dryRunPlan
+	// transactions counts the open transactions, changes they couldn't undo are refused meanwhile
+	transactions int
+}
+
+func NewFileWriter(mode WriteMode) *FileWriter {
+	return &FileWriter{mode: mode, planned: newDryRunPlan()}
+}
+
+// SetMode switches the write mode, starting a dry run over from the disk as it is
+func (w *FileWriter) SetMode(mode WriteMode) {
+	slog.Debug("Write mode set", "mode", mode)
+	w.mode = mode
+	w.planned = newDryRunPlan()
+}
+
+func (w *FileWriter) IsDryRun() bool {
+	return w != nil && w.mode == WriteDryRun
+}
+
+func (w *FileWriter) IsReadOnly() bool {
+	return w != nil && w.mode == WriteReadOnly
+}
+
+// skip tells the caller to leave the disk alone. Read-only mode refuses the change, a dry run first fails
+// where the real call would, using check, then records it.
+func (w *FileWriter) skip(op string, path string, newPath string, check func() error) (bool, error) {
+	if w.mode == WriteReadOnly {
+		return true, fmt.Errorf("%w: cannot %s %s", ErrReadOnly, op, path)
+	}
+	if w.transactions > 0 && (op == ChangeWrite || op == ChangeDelete) {
+		return true, fmt.Errorf("cannot %s %s in a transaction, it couldn't be rolled back", op, path)
+	}
+	if w.mode != WriteDryRun {
+		return false, nil
+	}
+
+	if check != nil {
+		if err := check(); err != nil {
+			return true, err
+		}
+	}
+	w.record(op, path, newPath)
+	return true, nil
+}
+
+// dryRunPlan is what a dry run pretends the disk looks like, so later steps see what earlier ones would have done
+type dryRunPlan struct {
+	// Paths created or moved in, true for directories
+	added map[string]bool
+	// Paths renamed away or deleted
+	gone map[string]bool
+	// Renamed paths and where their content still is on disk
+	moved   map[string]string
+	content map[string][]byte
+}
+
+func newDryRunPlan() *dryRunPlan {
+	return &dryRunPlan{
+		added:   map[string]bool{},
+		gone:    map[string]bool{},
+		moved:   map[string]string{},
+		content: map[string][]byte{},
+	}
+}
+
+func (p *dryRunPlan) clone() *dryRunPlan {
+	return &dryRunPlan{
+		added:   maps.Clone(p.added),
+		gone:    maps.Clone(p.gone),
+		moved:   maps.Clone(p.moved),
+		content: maps.Clone(p.content),
+	}
+}
+
+// realPath finds where path's content is on disk, following planned renames of it or its parents
+func (p *dryRunPlan) realPath(path string) string {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if old, ok := p.moved[dir]; ok {
+			return p.realPath(old + strings.TrimPrefix(path, dir))
+		}
+		if filepath.Dir(dir) == dir {
+			return path
+		}
+	}
+}
+
+// stat reports whether path exists once the plan is applied and whether it's a directory
+func (p *dryRunPlan) stat(path string) (exists bool, isDir bool) {
+	if isDir, ok := p.added[path]; ok {
+		return true, isDir
+	}
+	for dir := path; filepath.Dir(dir) != dir; dir = filepath.Dir(dir) {
+		if p.gone[dir] {
+			return false, false
+		}
+	}
+	info, err := os.Lstat(p.realPath(path))
+	if err != nil {
+		return false, false
+	}
+	return true, info.IsDir()
+}
+
+// list reads a directory as the plan would leave it
+func (p *dryRunPlan) list(path string) ([]fs.DirEntry, error) {
+	var listed []fs.DirEntry
+	_, created := p.added[path]
+	_, renamed := p.moved[path]
+	if !created || renamed {
+		dirEntries, err := os.ReadDir(p.realPath(path))
+		if err != nil {
+			return nil, err
+		}
+		for _, dirEntry := range dirEntries {
+			childPath := filepath.Join(path, dirEntry.Name())
+			if _, ok := p.added[childPath]; !ok && !p.gone[childPath] {
+				listed = append(listed, dirEntry)
+			}
+		}
+	}
+
+	for childPath, isDir := range p.added {
+		if filepath.Dir(childPath) == path {
+			listed = append(listed, plannedDirEntry{name: filepath.Base(childPath), isDir: isDir})
+		}
+	}
+	slices.SortFunc(listed, func(a, b fs.DirEntry) int { return cmp.Compare(a.Name(), b.Name()) })
+	return listed, nil
+}
+
+// forget drops everything planned at or below path
+func (p *dryRunPlan) forget(path string) {
+	under := func(key string) bool {
+		return key == path || strings.HasPrefix(key, path+string(filepath.Separator))
+	}
+	maps.DeleteFunc(p.added, func(key string, _ bool) bool { return under(key) })
+	maps.DeleteFunc(p.moved, func(key string, _ string) bool { return under(key) })
+	maps.DeleteFunc(p.content, func(key string, _ []byte) bool { return under(key) })
+}
+
+func (p *dryRunPlan) rename(oldPath string, newPath string) {
+	_, isDir := p.stat(oldPath)
+	_, created := p.added[oldPath]
+	_, renamed := p.moved[oldPath]
+
+	prefix := oldPath + string(filepath.Separator)
+	for _, m := range []map[string]bool{p.added, p.gone} {
+		for key, value := range maps.Clone(m) {
+			if strings.HasPrefix(key, prefix) {
+				delete(m, key)
+				m[newPath+strings.TrimPrefix(key, oldPath)] = value
+			}
+		}
+	}
+	for key, value := range maps.Clone(p.moved) {
+		if strings.HasPrefix(key, prefix) {
+			delete(p.moved, key)
+			p.moved[newPath+strings.TrimPrefix(key, oldPath)] = value
+		}
+	}
+	for key, value := range maps.Clone(p.content) {
+		if key == oldPath || strings.HasPrefix(key, prefix) {
+			delete(p.content, key)
+			p.content[newPath+strings.TrimPrefix(key, oldPath)] = value
+		}
+	}
+
+	if !created || renamed {
+		p.moved[newPath] = p.realPath(oldPath)
+	}
+	delete(p.added, oldPath)
+	delete(p.moved, oldPath)
+	p.gone[oldPath] = true
+	p.added[newPath] = isDir
+	delete(p.gone, newPath)
+}
+
+func (p *dryRunPlan) remove(path string) {
+	p.forget(path)
+	p.gone[path] = true
+}
+
+func (p *dryRunPlan) mkdir(path string) {
+	p.added[path] = true
+	delete(p.gone, path)
+}
+
+func (p *dryRunPlan) write(path string, data []byte) {
+	if exists, _ := p.stat(path); !exists {
+		p.added[path] = false
+		delete(p.gone, path)
+	}
+	p.content[path] = data
+}
+
+type plannedDirEntry struct {
+	name  string
+	isDir bool
+}
+
+func (e plannedDirEntry) Name() string { return e.name }
+func (e plannedDirEntry) IsDir() bool  { return e.isDir }
+func (e plannedDirEntry) Type() fs.FileMode {
+	if e.isDir {
+		return fs.ModeDir
+	}
+	return 0
+}
+func (e plannedDirEntry) Info() (fs.FileInfo, error) { return nil, fs.ErrNotExist }
+
+func (w *FileWriter) exists(path string) bool {
+	if w.IsDryRun() {
+		exists, _ := w.planned.stat(path)
+		return exists
+	}
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// readDir lists a directory, as a dry run would have left it
+func (w *FileWriter) readDir(path string) ([]fs.DirEntry, error) {
+	if w.IsDryRun() {
+		return w.planned.list(path)
+	}
+	return os.ReadDir(path)
+}
+
+// readFile reads path, including files a dry run has only pretended to write
+func (w *FileWriter) readFile(path string) ([]byte, error) {
+	if w.IsDryRun() {
+		if data, ok := w.planned.content[path]; ok {
+			return data, nil
+		}
+		path = w.planned.realPath(path)
+	}
+	return os.ReadFile(path)
+}
+
+func (w *FileWriter) record(op string, path string, newPath string) {
+	slog.Debug("Recorded change", "op", op, "path", path, "newPath", newPath)
+	w.changes = append(w.changes, Change{Op: op, Path: path, NewPath: newPath})
+}
+
+// TakeChanges returns the changes recorded since the last call and clears them
+func (w *FileWriter) TakeChanges() []Change {
+	taken := w.changes
+	w.changes = nil
+	return taken
+}
+
+// Transaction marks a point in the recorded changes that everything after it can be undone back to.
+// Only renames, created files and directories can be undone, so overwriting or deleting fails while one is open.
+// Every transaction ends with Commit or Rollback, they can be nested.
+type Transaction struct {
+	files   *FileWriter
+	start   int
+	planned *dryRunPlan
+	done    bool
+}
+
+func (w *FileWriter) BeginTransaction() *Transaction {
+	w.transactions++
+	tx := &Transaction{files: w, start: len(w.changes)}
+	if w.mode == WriteDryRun {
+		tx.planned = w.planned.clone()
+	}
+	return tx
+}
+
+// Commit keeps the changes made since the transaction began
+func (t *Transaction) Commit() {
+	if !t.done {
+		t.done = true
+		t.files.transactions--
+	}
+}
+
+// Rollback undoes the changes recorded since the transaction began, newest first, and forgets them.
+// A dry run goes back to what it planned when the transaction began.
+func (t *Transaction) Rollback() error {
+	w := t.files
+	defer t.Commit()
+	start := min(t.start, len(w.changes))
+
+	if w.mode == WriteDryRun {
+		w.changes = w.changes[:start]
+		if t.planned != nil {
+			w.planned = t.planned
+		}
+		return nil
+	}
+
+	var errs []error
+	for i := len(w.changes) - 1; i >= start; i-- {
+		change := w.changes[i]
+		slog.Debug("Rolling back change", "op", change.Op, "path", change.Path, "newPath", change.NewPath)
+
+		var err error
+		switch change.Op {
+		case ChangeRename:
+			err = os.Rename(change.NewPath, change.Path)
+		case ChangeCreate, ChangeMkdir:
+			err = os.Remove(change.Path)
+		default:
+			err = fmt.Errorf("cannot undo %s of %s", change.Op, change.Path)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	w.changes = w.changes[:start]
+	return errors.Join(errs...)
+}
+
+// Abort rolls the transaction back after err and returns err, joined with the rollback's error if that failed too
+func (t *Transaction) Abort(err error) error {
+	if rollbackErr := t.Rollback(); rollbackErr != nil {
+		return errors.Join(err, fmt.Errorf("rollback failed: %w", rollbackErr))
+	}
+	return err
+}
+
+func (w *FileWriter) rename(oldPath string, newPath string) error {
+	check := func() error {
+		if !w.exists(oldPath) {
+			return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.ENOENT}
+		}
+		return nil
+	}
+	if skip, err := w.skip(ChangeRename, oldPath, newPath, check); skip {
+		if err == nil {
+			w.planned.rename(oldPath, newPath)
+		}
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	w.record(ChangeRename, oldPath, newPath)
+	return nil
+}
+
+// remove deletes a file or an empty directory
+func (w *FileWriter) remove(path string) error {
+	check := func() error {
+		exists, isDir := w.planned.stat(path)
+		if !exists {
+			return &os.PathError{Op: "remove", Path: path, Err: syscall.ENOENT}
+		}
+		if isDir {
+			if children, err := w.planned.list(path); err != nil || len(children) > 0 {
+				return &os.PathError{Op: "remove", Path: path, Err: syscall.ENOTEMPTY}
+			}
+		}
+		return nil
+	}
+	if skip, err := w.skip(ChangeDelete, path, "", check); skip {
+		if err == nil {
+			w.planned.remove(path)
+		}
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	w.record(ChangeDelete, path, "")
+	return nil
+}
+
+func (w *FileWriter) removeAll(path string) error {
+	if skip, err := w.skip(ChangeDelete, path, "", nil); skip {
+		if err == nil {
+			w.planned.remove(path)
+		}
+		return err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	w.record(ChangeDelete, path, "")
+	return nil
+}
+
+func (w *FileWriter) mkdir(path string) error {
+	check := func() error {
+		if w.exists(path) {
+			return &os.PathError{Op: "mkdir", Path: path, Err: syscall.EEXIST}
+		}
+		if _, parentIsDir := w.planned.stat(filepath.Dir(path)); !parentIsDir {
+			return &os.PathError{Op: "mkdir", Path: path, Err: syscall.ENOENT}
+		}
+		return nil
+	}
+	if skip, err := w.skip(ChangeMkdir, path, "", check); skip {
+		if err == nil {
+			w.planned.mkdir(path)
+		}
+		return err
+	}
+	if err := os.Mkdir(path, 0755); err != nil {
+		return err
+	}
+	w.record(ChangeMkdir, path, "")
+	return nil
+}
+
+// mkdirAll creates path along with any missing parents, recording each directory it creates
+func (w *FileWriter) mkdirAll(path string) error {
+	if w.IsDryRun() {
+		if _, isDir := w.planned.stat(path); isDir {
+			return nil
+		}
+	} else if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return nil
+	}
+	if parent := filepath.Dir(path); parent != path {
+		if err := w.mkdirAll(parent); err != nil {
+			return err
+		}
+	}
+	return w.mkdir(path)
+}
+
+// createExclusive creates path with content, failing with fs.ErrExist instead of truncating an existing file
+func (w *FileWriter) createExclusive(path string, content string) error {
+	check := func() error {
+		if w.exists(path) {
+			return &os.PathError{Op: "open", Path: path, Err: fs.ErrExist}
+		}
+		return nil
+	}
+	if skip, err := w.skip(ChangeCreate, path, "", check); skip {
+		if err == nil {
+			w.planned.write(path, []byte(content))
+		}
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
+		return err
+	}
+	w.record(ChangeCreate, path, "")
+	return nil
+}
+
+// writeAtomic writes data next to path and renames it into place, so readers never see a partial note
+func (w *FileWriter) writeAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	op := ChangeCreate
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+		op = ChangeWrite
+	} else if w.IsDryRun() && w.exists(path) {
+		op = ChangeWrite
+	}
+	if skip, err := w.skip(op, path, "", nil); skip {
+		if err == nil {
+			w.planned.write(path, data)
+		}
+		return err
+	}
+
+	if err := replaceFile(path, data, mode); err != nil {
+		return err
+	}
+
+	w.record(op, path, "")
+	return nil
+}
+
+// replaceFile writes data to a synced temp file next to path and renames it over path without recording a change
+func replaceFile(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", path, err)
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+)
+
+// newTestTree creates files and directories, those ending in /, below a temp directory and returns it
+func newTestTree(t *testing.T, paths ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, path := range paths {
+		absPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if path[len(path)-1] == '/' {
+			if err := os.MkdirAll(absPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.WriteFile(absPath, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func listTree(t *testing.T, w *FileWriter, root string) []string {
+	t.Helper()
+	var paths []string
+	var walk func(dir string)
+	walk = func(dir string) {
+		dirEntries, err := w.readDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, dirEntry := range dirEntries {
+			path := filepath.Join(dir, dirEntry.Name())
+			rel, _ := filepath.Rel(root, path)
+			if dirEntry.IsDir() {
+				paths = append(paths, rel+"/")
+				walk(path)
+				continue
+			}
+			paths = append(paths, rel)
+		}
+	}
+	walk(root)
+	return paths
+}
+
+// TestDryRunMatchesDisk runs the same steps in a dry run and for real, both must fail alike and leave the same tree
+func TestDryRunMatchesDisk(t *testing.T) {
+	tests := []struct {
+		name    string
+		tree    []string
+		steps   func(w *FileWriter, root string) error
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "rename then write",
+			tree: []string{"a/note.md"},
+			steps: func(w *FileWriter, root string) error {
+				if err := w.rename(filepath.Join(root, "a"), filepath.Join(root, "b")); err != nil {
+					return err
+				}
+				return w.writeAtomic(filepath.Join(root, "b", "new.md"), []byte("new"))
+			},
+			want: []string{"b/", "b/new.md", "b/note.md"},
+		},
+		{
+			name: "remove empty directory",
+			tree: []string{"a/", "b.md"},
+			steps: func(w *FileWriter, root string) error {
+				return w.remove(filepath.Join(root, "a"))
+			},
+			want: []string{"b.md"},
+		},
+		{
+			name: "remove non-empty directory",
+			tree: []string{"a/note.md"},
+			steps: func(w *FileWriter, root string) error {
+				return w.remove(filepath.Join(root, "a"))
+			},
+			want:    []string{"a/", "a/note.md"},
+			wantErr: syscall.ENOTEMPTY,
+		},
+		{
+			name: "remove directory emptied earlier",
+			tree: []string{"a/note.md"},
+			steps: func(w *FileWriter, root string) error {
+				if err := w.remove(filepath.Join(root, "a", "note.md")); err != nil {
+					return err
+				}
+				return w.remove(filepath.Join(root, "a"))
+			},
+			want: []string{},
+		},
+		{
+			name: "remove missing file",
+			tree: []string{"a.md"},
+			steps: func(w *FileWriter, root string) error {
+				return w.remove(filepath.Join(root, "b.md"))
+			},
+			want:    []string{"a.md"},
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name: "rename missing file",
+			tree: []string{"a.md"},
+			steps: func(w *FileWriter, root string) error {
+				return w.rename(filepath.Join(root, "b.md"), filepath.Join(root, "c.md"))
+			},
+			want:    []string{"a.md"},
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name: "mkdir existing directory",
+			tree: []string{"a/"},
+			steps: func(w *FileWriter, root string) error {
+				return w.mkdir(filepath.Join(root, "a"))
+			},
+			want:    []string{"a/"},
+			wantErr: fs.ErrExist,
+		},
+		{
+			name: "mkdir without parent",
+			tree: []string{},
+			steps: func(w *FileWriter, root string) error {
+				return w.mkdir(filepath.Join(root, "a", "b"))
+			},
+			want:    []string{},
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name: "create existing file",
+			tree: []string{"a.md"},
+			steps: func(w *FileWriter, root string) error {
+				return w.createExclusive(filepath.Join(root, "a.md"), "again")
+			},
+			want:    []string{"a.md"},
+			wantErr: fs.ErrExist,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []WriteMode{WriteDryRun, WriteEnabled} {
+			name := tt.name
+			if mode == WriteDryRun {
+				name += " dry run"
+			}
+			t.Run(name, func(t *testing.T) {
+				root := newTestTree(t, tt.tree...)
+				w := NewFileWriter(mode)
+
+				err := tt.steps(w, root)
+				if tt.wantErr == nil && err != nil {
+					t.Fatalf("error = %v", err)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				if got := listTree(t, w, root); !slices.Equal(got, tt.want) && !(len(got) == 0 && len(tt.want) == 0) {
+					t.Errorf("tree = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestDryRunLeavesDiskAlone(t *testing.T) {
+	root := newTestTree(t, "a/note.md")
+	w := NewFileWriter(WriteDryRun)
+
+	notePath := filepath.Join(root, "a", "note.md")
+	if err := w.writeAtomic(notePath, []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.rename(filepath.Join(root, "a"), filepath.Join(root, "b")); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := w.readFile(filepath.Join(root, "b", "note.md")); err != nil || string(data) != "changed" {
+		t.Errorf("planned content = %q, %v, want %q", data, err, "changed")
+	}
+	if data, err := os.ReadFile(notePath); err != nil || string(data) != "a/note.md" {
+		t.Errorf("disk content = %q, %v, want it untouched", data, err)
+	}
+	if got := len(w.TakeChanges()); got != 2 {
+		t.Errorf("recorded %d changes, want 2", got)
+	}
+}
+
+func TestReadOnlyRefusesChanges(t *testing.T) {
+	root := newTestTree(t, "a.md")
+	w := NewFileWriter(WriteReadOnly)
+
+	steps := map[string]func() error{
+		"rename": func() error { return w.rename(filepath.Join(root, "a.md"), filepath.Join(root, "b.md")) },
+		"remove": func() error { return w.remove(filepath.Join(root, "a.md")) },
+		"mkdir":  func() error { return w.mkdir(filepath.Join(root, "dir")) },
+		"create": func() error { return w.createExclusive(filepath.Join(root, "c.md"), "") },
+		"write":  func() error { return w.writeAtomic(filepath.Join(root, "a.md"), nil) },
+	}
+	for name, step := range steps {
+		t.Run(name, func(t *testing.T) {
+			if err := step(); !errors.Is(err, ErrReadOnly) {
+				t.Errorf("error = %v, want %v", err, ErrReadOnly)
+			}
+		})
+	}
+	if got := listTree(t, w, root); !slices.Equal(got, []string{"a.md"}) {
+		t.Errorf("tree = %v, want it untouched", got)
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	for _, mode := range []WriteMode{WriteEnabled, WriteDryRun} {
+		t.Run(map[WriteMode]string{WriteEnabled: "disk", WriteDryRun: "dry run"}[mode], func(t *testing.T) {
+			root := newTestTree(t, "01. a.md", "02. b.md")
+			w := NewFileWriter(mode)
+			before := listTree(t, w, root)
+
+			outer := w.BeginTransaction()
+			if err := w.mkdir(filepath.Join(root, "dir")); err != nil {
+				t.Fatal(err)
+			}
+			inner := w.BeginTransaction()
+			if err := w.rename(filepath.Join(root, "01. a.md"), filepath.Join(root, "dir", "01. a.md")); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.createExclusive(filepath.Join(root, "dir", "c.md"), "c"); err != nil {
+				t.Fatal(err)
+			}
+			inner.Commit()
+			if err := w.rename(filepath.Join(root, "02. b.md"), filepath.Join(root, "01. b.md")); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := outer.Rollback(); err != nil {
+				t.Fatalf("Rollback() error = %v", err)
+			}
+			if got := listTree(t, w, root); !slices.Equal(got, before) {
+				t.Errorf("tree after rollback = %v, want %v", got, before)
+			}
+			if changes := w.TakeChanges(); len(changes) != 0 {
+				t.Errorf("changes after rollback = %v, want none", changes)
+			}
+		})
+	}
+}
+
+func TestTransactionRefusesChangesItCantUndo(t *testing.T) {
+	root := newTestTree(t, "a.md")
+	w := NewFileWriter(WriteEnabled)
+
+	tx := w.BeginTransaction()
+	if err := w.writeAtomic(filepath.Join(root, "a.md"), []byte("overwritten")); err == nil {
+		t.Error("overwriting in a transaction succeeded")
+	}
+	if err := w.remove(filepath.Join(root, "a.md")); err == nil {
+		t.Error("deleting in a transaction succeeded")
+	}
+	tx.Commit()
+
+	if data, _ := os.ReadFile(filepath.Join(root, "a.md")); string(data) != "a.md" {
+		t.Errorf("content = %q, want it untouched", data)
+	}
+	if err := w.remove(filepath.Join(root, "a.md")); err != nil {
+		t.Errorf("deleting after the transaction error = %v", err)
+	}
+}
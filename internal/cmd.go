@@ -1,16 +1,25 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 )
 
 const (
+	RofiExitCodeCancel   = 1  // Escape
 	RofiExitCodeMoveDown = 10 // Ctrl+Alt+J
 	RofiExitCodeMoveUp   = 11 // Ctrl+Alt+K
 	RofiExitCodeDelete   = 12 // Ctrl+Alt+K
+	RofiExitCodePin      = 13 // Ctrl+Alt+P
+	RofiExitCodeBack     = 14 // Alt+H
+	RofiExitCodeForward  = 15 // Alt+L
+	RofiExitCodeLinks    = 16 // Alt+B
 )
 
 func (m *MenuState) launchMenu() (string, error) {
@@ -20,6 +29,9 @@ func (m *MenuState) launchMenu() (string, error) {
 	}
 
 	args := []string{"notes", "-dmenu", "-l", "10", "-i", "-p", m.getPrompt()}
+	if m.Mode == ModeQuickSwitch || m.Mode == ModeRecent || m.Mode == ModeTagged {
+		args = append(args, "-matching", "fuzzy")
+	}
 
 	if m.Selection != "" {
 		for i, item := range items {
@@ -42,20 +54,55 @@ func (m *MenuState) launchMenu() (string, error) {
 	output, err := cmd.Output()
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
+			// Only moving and deleting change the garden, pins and history still work in read-only mode
+			gardenKeys := []int{RofiExitCodeMoveDown, RofiExitCodeMoveUp, RofiExitCodeDelete}
+			if m.notes.IsReadOnly() && slices.Contains(gardenKeys, exitError.ExitCode()) {
+				slog.Debug("Ignoring menu action in read-only mode", "exitCode", exitError.ExitCode())
+				return "", nil
+			}
 			selection := strings.TrimSpace(string(output))
 			entry := m.nav.CurrentDirectory().GetEntryByFilename(selection)
+			// Other modes list breadcrumbs and actions, not the entries of the current directory
+			if slices.Contains(gardenKeys, exitError.ExitCode()) && (m.Mode != ModeBrowse || entry == nil) {
+				slog.Debug("Ignoring menu action without a highlighted entry", "exitCode", exitError.ExitCode(), "mode", m.Mode)
+				return "", nil
+			}
 			switch exitError.ExitCode() {
 			case RofiExitCodeMoveDown:
-				m.nav.CurrentDirectory().MoveEntryDown(entry)
+				err := m.nav.CurrentDirectory().MoveEntryDown(entry)
 				m.Selection = entry.String()
-				return "", nil
+				return "", err
 			case RofiExitCodeMoveUp:
-				m.nav.CurrentDirectory().MoveEntryUp(entry)
+				err := m.nav.CurrentDirectory().MoveEntryUp(entry)
 				m.Selection = entry.String()
-				return "", nil
+				return "", err
 			case RofiExitCodeDelete:
-				m.nav.CurrentDirectory().DeleteEntry(entry)
+				return "", m.nav.CurrentDirectory().DeleteEntry(entry)
+			case RofiExitCodePin:
+				if m.Mode != ModeBrowse {
+					return "", nil
+				}
+				m.Selection = selection
+				return "", m.togglePin(selection)
+			case RofiExitCodeBack, RofiExitCodeForward:
+				if m.Mode != ModeBrowse {
+					return "", nil
+				}
+				m.Selection = ""
+				if exitError.ExitCode() == RofiExitCodeBack {
+					return "", m.nav.Back()
+				}
+				return "", m.nav.Forward()
+			case RofiExitCodeLinks:
+				if m.Mode != ModeBrowse || entry == nil || entry.IsDir {
+					return "", nil
+				}
+				m.Selection = selection
+				m.linkNote = filepath.Join(m.nav.CurrentDirectory().Path, entry.String())
+				m.Mode = ModeLinks
 				return "", nil
+			case RofiExitCodeCancel:
+				return "", ErrCancelled
 			}
 		}
 		return "", fmt.Errorf("rofi command failed in %s mode: %w", m.Mode, err)
@@ -64,3 +111,43 @@ func (m *MenuState) launchMenu() (string, error) {
 
 	return selection, nil
 }
+
+// PromptText asks for free text through the menu backend without offering any items
+func PromptText(prompt string) (string, error) {
+	cmd := exec.Command("rofi-launcher", "notes", "-dmenu", "-l", "0", "-p", prompt)
+	cmd.Stdin = strings.NewReader("")
+
+	output, err := cmd.Output()
+	if isRofiCancel(err) {
+		return "", ErrCancelled
+	}
+	if err != nil {
+		return "", fmt.Errorf("rofi prompt failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// PickFromMenu asks the user to choose one of items through the menu backend
+func PickFromMenu(prompt string, items []string) (string, error) {
+	cmd := exec.Command("rofi-launcher", "notes", "-dmenu", "-l", "10", "-i", "-p", prompt)
+	cmd.Stdin = strings.NewReader(strings.Join(items, "\n"))
+
+	output, err := cmd.Output()
+	if isRofiCancel(err) {
+		return "", ErrCancelled
+	}
+	if err != nil {
+		return "", fmt.Errorf("rofi picker failed: %w", err)
+	}
+
+	choice := strings.TrimSpace(string(output))
+	if !slices.Contains(items, choice) {
+		return "", fmt.Errorf("no valid choice picked: %q", choice)
+	}
+	return choice, nil
+}
+
+func isRofiCancel(err error) bool {
+	var exitError *exec.ExitError
+	return errors.As(err, &exitError) && exitError.ExitCode() == RofiExitCodeCancel
+}
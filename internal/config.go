@@ -1,27 +1,232 @@
 package internal
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 type Config struct {
-	RootDir     string
-	InboxDir    string
-	TemplateDir string
+	RootDir     string          `json:"rootDir"`
+	InboxDir    string          `json:"inboxDir"`
+	TemplateDir string          `json:"templateDir"`
+	ArchiveDir  string          `json:"archiveDir"`
+	OnCollision CollisionPolicy `json:"onCollision"`
+	ReadOnly    bool            `json:"readOnly"`
+	Ignore      []string        `json:"ignore"`
+	// RestoreSession reopens the menu in the directory it was left in
+	RestoreSession bool           `json:"restoreSession"`
+	Daily          PeriodicConfig `json:"daily"`
+	Weekly         PeriodicConfig `json:"weekly"`
+	Monthly        PeriodicConfig `json:"monthly"`
+	Quarterly      PeriodicConfig `json:"quarterly"`
+	Yearly         PeriodicConfig `json:"yearly"`
+	Capture        CaptureConfig  `json:"capture"`
 }
 
-func LoadConfig() (*Config, error) {
-	rootDir := os.Getenv("GARDEN_LOG_DIR")
-	if rootDir == "" {
-		return nil, fmt.Errorf("GARDEN_LOG_DIR environment variable is not set")
+// PeriodicConfig describes where a periodic note lives and what it starts from.
+// Path is relative to the root directory and may contain date tokens like {{YYYY-MM-DD}}.
+// Template is the name of a note in the template directory, with or without its index.
+type PeriodicConfig struct {
+	Path     string `json:"path"`
+	Template string `json:"template"`
+}
+
+// CaptureConfig controls where quick captures end up.
+// Path is a note path pattern like PeriodicConfig.Path, Heading optionally targets a section in it
+// and Format is either "bullet" or "block".
+type CaptureConfig struct {
+	Path    string `json:"path"`
+	Heading string `json:"heading"`
+	Format  string `json:"format"`
+}
+
+// CollisionPolicy decides what happens when a new entry would replace an existing one
+type CollisionPolicy int
+
+const (
+	CollisionOpen CollisionPolicy = iota
+	CollisionSuffix
+	CollisionAbort
+)
+
+func (p CollisionPolicy) String() string {
+	switch p {
+	case CollisionOpen:
+		return "open"
+	case CollisionSuffix:
+		return "suffix"
+	case CollisionAbort:
+		return "abort"
+	default:
+		return ""
 	}
+}
+
+func (p CollisionPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *CollisionPolicy) UnmarshalText(text []byte) error {
+	policy, err := ParseCollisionPolicy(string(text))
+	if err != nil {
+		return err
+	}
+	*p = policy
+	return nil
+}
+
+func ParseCollisionPolicy(value string) (CollisionPolicy, error) {
+	switch value {
+	case "open":
+		return CollisionOpen, nil
+	case "suffix":
+		return CollisionSuffix, nil
+	case "abort":
+		return CollisionAbort, nil
+	default:
+		return CollisionOpen, fmt.Errorf("%w: unknown collision policy %q (expected open, suffix or abort)", ErrValidation, value)
+	}
+}
+
+func DefaultConfig() *Config {
 	return &Config{
-		RootDir:     rootDir,
-		InboxDir:    "01. Inbox",
-		TemplateDir: "05. Archive/01. Templates",
-	}, nil
+		InboxDir:       "01. Inbox",
+		TemplateDir:    "05. Archive/01. Templates",
+		ArchiveDir:     "05. Archive",
+		OnCollision:    CollisionOpen,
+		RestoreSession: true,
+		Daily: PeriodicConfig{
+			Path: "01. Inbox/{{YYYY-MM-DD}}.md",
+		},
+		Weekly: PeriodicConfig{
+			Path: "01. Inbox/{{GGGG}}-W{{WW}}.md",
+		},
+		Monthly: PeriodicConfig{
+			Path: "01. Inbox/{{YYYY-MM}}.md",
+		},
+		Quarterly: PeriodicConfig{
+			Path: "01. Inbox/{{YYYY}}-Q{{Q}}.md",
+		},
+		Yearly: PeriodicConfig{
+			Path: "01. Inbox/{{YYYY}}.md",
+		},
+		Capture: CaptureConfig{
+			Path:   "01. Inbox/Capture.md",
+			Format: "bullet",
+		},
+	}
+}
+
+func (c *Config) Periodic(period Period) PeriodicConfig {
+	switch period {
+	case PeriodWeek:
+		return c.Weekly
+	case PeriodMonth:
+		return c.Monthly
+	case PeriodQuarter:
+		return c.Quarterly
+	case PeriodYear:
+		return c.Yearly
+	default:
+		return c.Daily
+	}
+}
+
+// ConfigPath returns the location of the config file, honouring GARDEN_LOGGER_CONFIG
+func ConfigPath() (string, error) {
+	if path := os.Getenv("GARDEN_LOGGER_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(configDir, "garden-logger", "config.json"), nil
+}
+
+// CacheDir returns the directory for data that can be rebuilt from the garden, honouring GARDEN_LOGGER_CACHE
+func CacheDir() (string, error) {
+	if path := os.Getenv("GARDEN_LOGGER_CACHE"); path != "" {
+		return path, nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "garden-logger"), nil
+}
+
+// LoadConfig reads the optional config file over the defaults, GARDEN_LOG_DIR takes precedence for the root
+func LoadConfig() (*Config, error) {
+	config := DefaultConfig()
+
+	configPath, err := ConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err == nil {
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("%w: failed to parse config file %s: %w", ErrConfig, configPath, err)
+		}
+		slog.Debug("Loaded config file", "path", configPath)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: failed to read config file %s: %w", ErrConfig, configPath, err)
+	}
+
+	if rootDir := os.Getenv("GARDEN_LOG_DIR"); rootDir != "" {
+		config.RootDir = rootDir
+	}
+	if config.RootDir == "" {
+		return nil, fmt.Errorf("%w: GARDEN_LOG_DIR environment variable is not set and no rootDir is configured in %s", ErrConfig, configPath)
+	}
+
+	for _, pattern := range config.Ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: invalid ignore pattern %q in %s: %w", ErrConfig, pattern, configPath, err)
+		}
+	}
+
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(config.RootDir, "~/") {
+		config.RootDir = filepath.Join(home, config.RootDir[2:])
+	}
+
+	return config, nil
+}
+
+// WriteConfig saves the service's config to ConfigPath, leaving an existing file alone unless force is set
+func (s *EntryService) WriteConfig(force bool) (string, bool, error) {
+	config := s.config
+	configPath, err := ConfigPath()
+	if err != nil {
+		return "", false, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+
+	if _, err := os.Stat(configPath); err == nil && !force {
+		slog.Debug("Config file already exists", "path", configPath)
+		return configPath, false, nil
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", false, err
+	}
+	if err := s.files.mkdirAll(filepath.Dir(configPath)); err != nil {
+		return "", false, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := s.files.writeAtomic(configPath, append(data, '\n')); err != nil {
+		return "", false, fmt.Errorf("failed to write config file %s: %w", configPath, err)
+	}
+	return configPath, true, nil
 }
 
 const (
@@ -35,6 +240,18 @@ const (
 	MenuBack                = "←   Back"
 	MenuSettings            = "   Settings"
 	MenuOpenCurrentFolder   = "   Open Current Folder"
+	MenuPeriodic            = "󰃰   Periodic Notes"
+	MenuCapture             = "󰸕   Quick Capture"
+	MenuSearch              = "   Search"
+	MenuQuickSwitch         = "   Go to Note"
+	MenuRecent              = "   Recent"
+	MenuTags                = "   Tags"
+	MenuPinSetting          = "   Pinned"
+	MenuFavoritePrefix      = "   "
+	MenuBacklinkPrefix      = "  "
+	MenuOutgoingPrefix      = "  "
+	MenuHistoryBack         = "   Previous Folder"
+	MenuHistoryForward      = "   Next Folder"
 )
 
 func InitLogger(verbose bool) {
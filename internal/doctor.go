@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return ""
+	}
+}
+
+// Finding is one problem the doctor found, Path is relative to the root
+type Finding struct {
+	Severity Severity
+	Kind     string
+	Path     string
+	Message  string
+	Fix      string
+	apply    func() error
+}
+
+// Finding kinds
+const (
+	FindingMissingDir = "missing-dir"
+	FindingIndexing   = "indexing"
+	FindingName       = "name"
+	FindingEmptyDir   = "empty-dir"
+	FindingUnreadable = "unreadable"
+	FindingBrokenLink = "broken-link"
+)
+
+// Fixable reports whether Apply can fix the finding without risking any content
+func (f *Finding) Fixable() bool {
+	return f.apply != nil
+}
+
+func (f *Finding) Apply() error {
+	if f.apply == nil {
+		return fmt.Errorf("%s finding for %s has no safe fix", f.Kind, f.Path)
+	}
+	return f.apply()
+}
+
+// diagnosis collects findings during the walk
+type diagnosis struct {
+	findings []Finding
+}
+
+// Diagnose walks the whole garden and reports problems, ordered so that applying fixes from last to first
+// never invalidates the path of a fix still to come
+func (s *EntryService) Diagnose() ([]Finding, error) {
+	d := &diagnosis{}
+
+	configured := []struct{ name, path string }{
+		{"inbox", s.config.InboxDir},
+		{"template", s.config.TemplateDir},
+		{"archive", s.config.ArchiveDir},
+	}
+	for _, dir := range configured {
+		if dir.path == "" {
+			continue
+		}
+		_, found, err := s.FindPath(dir.path)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			continue
+		}
+
+		dirPath := dir.path
+		d.findings = append(d.findings, Finding{
+			Severity: SeverityError,
+			Kind:     FindingMissingDir,
+			Path:     dirPath,
+			Message:  fmt.Sprintf("configured %s directory doesn't exist", dir.name),
+			Fix:      "create it",
+			apply: func() error {
+				_, err := s.EnsureDirectory(dirPath)
+				return err
+			},
+		})
+	}
+
+	if err := s.diagnoseDirectory("", d); err != nil {
+		return nil, err
+	}
+
+	// Link fixes come last so they're applied before any repair renames the notes they rewrite
+	if err := s.diagnoseLinks(d); err != nil {
+		return nil, err
+	}
+
+	slog.Debug("Diagnosed garden", "findings", len(d.findings))
+	return d.findings, nil
+}
+
+func (s *EntryService) diagnoseDirectory(dirPath string, d *diagnosis) error {
+	dir, err := s.LoadDirectory(dirPath)
+	if err != nil {
+		d.findings = append(d.findings, Finding{
+			Severity: SeverityError,
+			Kind:     FindingUnreadable,
+			Path:     dirPath,
+			Message:  fmt.Sprintf("directory can't be read: %v", errors.Unwrap(err)),
+			Fix:      "check its permissions",
+		})
+		return nil
+	}
+
+	if len(dir.Entries) == 0 && dirPath != "" {
+		d.findings = append(d.findings, Finding{
+			Severity: SeverityInfo,
+			Kind:     FindingEmptyDir,
+			Path:     dirPath,
+			Message:  "directory is empty",
+			Fix:      "remove it if it's not needed",
+		})
+	}
+
+	if err := dir.ValidateIndexing(); err != nil {
+		d.findings = append(d.findings, Finding{
+			Severity: SeverityWarning,
+			Kind:     FindingIndexing,
+			Path:     dirPath,
+			Message:  err.Error(),
+			Fix:      "repair the indexing",
+			apply:    s.repairIndexing(dirPath),
+		})
+	}
+
+	dirEntries, err := s.files.readDir(dir.AbsPath)
+	if err != nil {
+		return err
+	}
+	mangled := map[string]bool{}
+	for _, dirEntry := range dirEntries {
+		if strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		entry, err := dir.LoadEntry(dirEntry)
+		if err != nil {
+			return err
+		}
+		if entry.String() == dirEntry.Name() {
+			continue
+		}
+
+		mangled[entry.String()] = true
+		suggested := *entry
+		suggested.Name = strings.ReplaceAll(strings.TrimSuffix(stripIndexPrefix(dirEntry.Name()), entry.Ext), ".", "-")
+		d.findings = append(d.findings, Finding{
+			Severity: SeverityError,
+			Kind:     FindingName,
+			Path:     filepath.Join(dirPath, dirEntry.Name()),
+			Message:  fmt.Sprintf("name is read as %q, dots in names aren't supported", entry.String()),
+			Fix:      fmt.Sprintf("rename it to %q", suggested.String()),
+		})
+	}
+
+	for _, entry := range dir.Entries {
+		if mangled[entry.String()] {
+			continue
+		}
+
+		entryPath := filepath.Join(dirPath, entry.String())
+		if entry.IsDir {
+			if err := s.diagnoseDirectory(entryPath, d); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if entry.Ext != ".md" {
+			continue
+		}
+		if _, err := s.files.readFile(entry.FilePath()); err != nil {
+			d.findings = append(d.findings, Finding{
+				Severity: SeverityError,
+				Kind:     FindingUnreadable,
+				Path:     entryPath,
+				Message:  fmt.Sprintf("note can't be read: %v", errors.Unwrap(err)),
+				Fix:      "check its permissions",
+			})
+		}
+	}
+	return nil
+}
+
+func (s *EntryService) repairIndexing(dirPath string) func() error {
+	return func() error {
+		dir, err := s.LoadDirectory(dirPath)
+		if err != nil {
+			return err
+		}
+		return dir.RepairIndexing()
+	}
+}
+
+// diagnoseLinks reports the links CheckLinks finds broken, stale ones can be rewritten to the note's current name
+func (s *EntryService) diagnoseLinks(d *diagnosis) error {
+	broken, err := s.CheckLinks()
+	if err != nil {
+		return err
+	}
+
+	for _, link := range broken {
+		finding := Finding{
+			Severity: SeverityWarning,
+			Kind:     FindingBrokenLink,
+			Path:     link.Source,
+		}
+		switch {
+		case link.Problem == LinkStale:
+			finding.Message = fmt.Sprintf("line %d: link %s points to an old name of %s", link.Line, link.Raw, link.Path)
+			finding.Fix = fmt.Sprintf("rewrite it to %s", link.Fix)
+			finding.apply = func() error {
+				_, err := s.FixLinks([]BrokenLink{link})
+				return err
+			}
+		case link.Kind == LinkWiki:
+			finding.Message = fmt.Sprintf("line %d: wikilink %s doesn't match any note", link.Line, link.Raw)
+			finding.Fix = "create the note or correct the link"
+		default:
+			finding.Message = fmt.Sprintf("line %d: link to %s points to a missing file", link.Line, link.Target)
+			finding.Fix = "correct or remove the link"
+		}
+		d.findings = append(d.findings, finding)
+	}
+	return nil
+}
@@ -21,6 +21,8 @@ type Entry struct {
 	Ext        string
 	IsDir      bool
 	ParentPath string
+	meta       Metadata
+	files      *FileWriter
 }
 
 func (e *Entry) IsAnchor() bool {
@@ -39,7 +41,7 @@ func (d *Directory) LoadEntry(dirEntry os.DirEntry) (*Entry, error) {
 		ext = filepath.Ext(dirEntry.Name())
 	}
 
-	entry := &Entry{index, name, ext, isDir, d.AbsPath}
+	entry := &Entry{index, name, ext, isDir, d.AbsPath, nil, d.files}
 	return entry, nil
 }
 
@@ -67,6 +69,13 @@ func parseEntryName(name string) (int, string, error) {
 
 }
 
+var indexPrefixPattern = regexp.MustCompile(`^\d{2}\.\s+`)
+
+// stripIndexPrefix removes a leading "NN. " from a filename
+func stripIndexPrefix(filename string) string {
+	return indexPrefixPattern.ReplaceAllString(filename, "")
+}
+
 func (e *Entry) String() string {
 	if e.EntryIndex == -1 {
 		return fmt.Sprintf("%s%s", e.Name, e.Ext)
@@ -84,17 +93,60 @@ func (e *Entry) Move(newIndex int) error {
 		return nil
 	}
 
+	if e.EntryIndex == newIndex {
+		return nil
+	}
+
 	oldPath := e.FilePath()
 	e.EntryIndex = newIndex
 	newPath := e.FilePath()
 
 	slog.Debug("Calling move entry on ", "entry", e.String(), "oldPath", oldPath, "newPath", newPath)
-	return os.Rename(oldPath, newPath)
+	return e.files.rename(oldPath, newPath)
+}
+
+// validateEntryName rejects names that would leave the directory or be read back as a different name
+func validateEntryName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: name can't be empty", ErrValidation)
+	}
+	if strings.ContainsAny(name, "/.") {
+		return fmt.Errorf("%w: name %q can't contain / or .", ErrValidation, name)
+	}
+	return nil
+}
+
+// Rename changes the entry's name in place, keeping its index and extension
+func (e *Entry) Rename(name string) error {
+	if err := validateEntryName(name); err != nil {
+		return err
+	}
+
+	oldPath := e.FilePath()
+	renamed := *e
+	renamed.Name = name
+	newPath := renamed.FilePath()
+
+	if e.files.exists(newPath) {
+		return fmt.Errorf("cannot rename %q: %w: %q", e.String(), ErrEntryExists, renamed.String())
+	}
+
+	slog.Debug("Calling rename entry on ", "entry", e.String(), "oldPath", oldPath, "newPath", newPath)
+	if err := e.files.rename(oldPath, newPath); err != nil {
+		return err
+	}
+	e.Name = name
+	return nil
 }
 
 func (e *Entry) Remove() error {
 	slog.Debug("Calling remove entry on ", "entry", e.String(), "path", e.ParentPath)
-	return os.Remove(e.FilePath())
+	return e.files.remove(e.FilePath())
+}
+
+func (e *Entry) RemoveAll() error {
+	slog.Debug("Calling recursive remove entry on ", "entry", e.String(), "path", e.ParentPath)
+	return e.files.removeAll(e.FilePath())
 }
 
 // Directories
@@ -104,6 +156,7 @@ type Directory struct {
 	AbsPath   string
 	IsIndexed bool
 	Entries   []*Entry
+	files     *FileWriter
 }
 
 func (d *Directory) GetEntryByIndex(index int) *Entry {
@@ -118,7 +171,7 @@ func (d *Directory) GetEntryByIndex(index int) *Entry {
 func (d *Directory) LoadEntries() error {
 	d.Entries = nil
 
-	dirEntries, err := os.ReadDir(d.AbsPath)
+	dirEntries, err := d.files.readDir(d.AbsPath)
 	if err != nil {
 		return fmt.Errorf("failed to list entries for %s: %w", d.Path, err)
 	}
@@ -135,11 +188,15 @@ func (d *Directory) LoadEntries() error {
 		d.Entries = append(d.Entries, entry)
 	}
 
-	slices.SortStableFunc(d.Entries, func(i, j *Entry) int { return cmp.Compare(i.EntryIndex, j.EntryIndex) })
+	d.sortEntries()
 
 	return nil
 }
 
+func (d *Directory) sortEntries() {
+	slices.SortStableFunc(d.Entries, func(i, j *Entry) int { return cmp.Compare(i.EntryIndex, j.EntryIndex) })
+}
+
 func (d *Directory) ListEntries() []string {
 
 	var entries []string
@@ -151,6 +208,29 @@ func (d *Directory) ListEntries() []string {
 	return entries
 }
 
+// GetEntryByName finds an entry by its cleaned name and extension, ignoring any index prefix
+func (d *Directory) GetEntryByName(name string, ext string) *Entry {
+	for _, entry := range d.Entries {
+		if entry.Name == name && entry.Ext == ext {
+			return entry
+		}
+	}
+	return nil
+}
+
+// FindEntry looks an entry up by filename, falling back to its cleaned name so index prefixes don't matter
+func (d *Directory) FindEntry(filename string) *Entry {
+	if entry := d.GetEntryByFilename(filename); entry != nil {
+		return entry
+	}
+
+	_, cleanName, err := parseEntryName(filename)
+	if err != nil {
+		return nil
+	}
+	return d.GetEntryByName(cleanName, filepath.Ext(stripIndexPrefix(filename)))
+}
+
 func (d *Directory) GetEntryByFilename(filename string) *Entry {
 	for _, entry := range d.Entries {
 		if entry.String() == filename {
@@ -163,14 +243,11 @@ func (d *Directory) GetEntryByFilename(filename string) *Entry {
 // Indexing
 // A lot of this indexing relies on the entries array maintaining sorting
 
-func LoadIsIndexed(absPath string) bool {
+// isIndexed reports whether the directory at absPath has a .index file
+func (w *FileWriter) isIndexed(absPath string) bool {
 	indexFilePath := filepath.Join(absPath, ".index")
 
-	if _, err := os.Stat(indexFilePath); os.IsNotExist(err) {
-		return false
-	}
-
-	return true
+	return w.exists(indexFilePath)
 }
 
 // | 0-index | 1-index |
@@ -187,24 +264,12 @@ func (d *Directory) MoveEntryUp(entry *Entry) error {
 		slog.Debug("Cannot move directory above position 1")
 		return nil
 	}
-	if !entry.IsDir && entry.EntryIndex <= d.NewDirIndex()-1 {
+	if !entry.IsDir && entry.EntryIndex <= d.NewDirIndex() {
 		slog.Debug("Cannot move entry: would conflict with directory ordering")
 		return nil
 	}
 
-	swapEntry := d.GetEntryByIndex(entry.EntryIndex - 1)
-
-	err := entry.Move(entry.EntryIndex - 1)
-	if err != nil {
-		return err
-	}
-
-	err = swapEntry.Move(entry.EntryIndex)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return d.swapEntry(entry, entry.EntryIndex-1)
 }
 
 func (d *Directory) MoveEntryDown(entry *Entry) error {
@@ -221,29 +286,99 @@ func (d *Directory) MoveEntryDown(entry *Entry) error {
 		return nil
 	}
 
-	swapEntry := d.GetEntryByIndex(entry.EntryIndex - 1)
+	return d.swapEntry(entry, entry.EntryIndex+1)
+}
+
+// swapEntry moves entry to newIndex and whatever held newIndex into entry's old slot
+func (d *Directory) swapEntry(entry *Entry, newIndex int) error {
+	oldIndex := entry.EntryIndex
+	swapEntry := d.GetEntryByIndex(newIndex)
 
-	err := entry.Move(entry.EntryIndex + 1)
-	if err != nil {
+	if err := entry.Move(newIndex); err != nil {
 		return err
 	}
 
-	err = swapEntry.Move(entry.EntryIndex)
-	if err != nil {
-		return err
+	if swapEntry != nil {
+		if err := swapEntry.Move(oldIndex); err != nil {
+			return err
+		}
 	}
 
+	d.sortEntries()
 	return nil
 }
 
+// MoveEntryTo moves entry up or down one slot at a time until it reaches index or can't move any further
+func (d *Directory) MoveEntryTo(entry *Entry, index int) error {
+	if !d.IsIndexed {
+		return fmt.Errorf("%w: cannot reorder entries in %s: directory is not indexed", ErrIndexConflict, d.Path)
+	}
+
+	for entry.EntryIndex != index {
+		previous := entry.EntryIndex
+
+		var err error
+		if index < entry.EntryIndex {
+			err = d.MoveEntryUp(entry)
+		} else {
+			err = d.MoveEntryDown(entry)
+		}
+		if err != nil {
+			return err
+		}
+
+		if entry.EntryIndex == previous {
+			break
+		}
+	}
+	return nil
+}
+
+// Reorder moves entry to a position given as up, down, top, bottom or an index
+func (d *Directory) Reorder(entry *Entry, position string) error {
+	switch position {
+	case "up":
+		return d.MoveEntryUp(entry)
+	case "down":
+		return d.MoveEntryDown(entry)
+	case "top":
+		return d.MoveEntryTo(entry, 1)
+	case "bottom":
+		return d.MoveEntryTo(entry, d.NewFileIndex()-1)
+	default:
+		index, err := ParseReorderPosition(position)
+		if err != nil {
+			return err
+		}
+		return d.MoveEntryTo(entry, index)
+	}
+}
+
+// ParseReorderPosition checks a position for Reorder, returning the index for numeric ones and -1 otherwise
+func ParseReorderPosition(position string) (int, error) {
+	switch position {
+	case "up", "down", "top", "bottom":
+		return -1, nil
+	}
+	index, err := strconv.Atoi(position)
+	if err != nil || index < 1 {
+		return -1, fmt.Errorf("%w: invalid position %q (expected up, down, top, bottom or a number)", ErrValidation, position)
+	}
+	return index, nil
+}
+
 func (d *Directory) UpdateIsIndex(isIndexed bool) error {
 	indexFilePath := filepath.Join(d.AbsPath, ".index")
 
+	if isIndexed == d.files.isIndexed(d.AbsPath) {
+		return nil
+	}
+
 	if isIndexed {
-		return os.WriteFile(indexFilePath, nil, 0644)
+		return d.files.writeAtomic(indexFilePath, nil)
 	}
 
-	return os.Remove(indexFilePath)
+	return d.files.remove(indexFilePath)
 }
 
 func (d *Directory) NewDirIndex() int {
@@ -284,55 +419,84 @@ func (d *Directory) NewFileIndex() int {
 }
 
 func (d *Directory) InsertEntry(e *Entry) error {
+	e.files = d.files
 	if e.EntryIndex == -1 {
 		// Non-indexed entry, just append to the list
 		d.Entries = append(d.Entries, e)
 		return nil
 	}
 
-	d.Entries = append(d.Entries, e)
 	for _, entry := range d.Entries {
 		if entry.IsAnchor() {
 			continue
 		}
 		if entry.EntryIndex >= e.EntryIndex {
-			entry.Move(entry.EntryIndex + 1)
+			if err := entry.Move(entry.EntryIndex + 1); err != nil {
+				return err
+			}
 		}
 	}
+	d.Entries = append(d.Entries, e)
 	return nil
 }
 
 func (d *Directory) DeleteEntry(e *Entry) error {
+	return d.deleteEntry(e, e.Remove)
+}
+
+// DeleteEntryRecursive deletes a directory entry along with everything inside it
+func (d *Directory) DeleteEntryRecursive(e *Entry) error {
+	return d.deleteEntry(e, e.RemoveAll)
+}
+
+func (d *Directory) deleteEntry(e *Entry, remove func() error) error {
 	if e.IsAnchor() {
 		slog.Debug("Cannot delete anchor entry")
 		return nil
 	}
 
-	if d.IsIndexed {
-		for i := e.EntryIndex - 1; i < len(d.Entries); i++ {
-			entry := d.Entries[i]
-			if e == entry || entry.IsAnchor() {
-				continue
-			}
-			err := entry.Move(entry.EntryIndex - 1)
-			if err != nil {
-				return err
-			}
-		}
+	if err := remove(); err != nil {
+		return err
 	}
 
-	e.Remove()
+	return d.DetachEntry(e)
+}
+
+// DetachEntry drops an entry that has left the directory and closes the gap it leaves in the indexing
+func (d *Directory) DetachEntry(e *Entry) error {
+	d.Entries = slices.DeleteFunc(d.Entries, func(entry *Entry) bool { return entry == e })
 
+	if !d.IsIndexed || e.EntryIndex < 1 {
+		return nil
+	}
+
+	for _, entry := range d.Entries {
+		if entry.IsAnchor() || entry.EntryIndex <= e.EntryIndex {
+			continue
+		}
+		if err := entry.Move(entry.EntryIndex - 1); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (d *Directory) ApplyNumericIndexing() error {
 	slog.Debug("Starting ApplyNumericIndexing", "path", d.Path, "currentEntries", len(d.Entries))
-	d.UpdateIsIndex(true)
+	if err := d.UpdateIsIndex(true); err != nil {
+		return err
+	}
+	d.IsIndexed = true
+
+	// Keep the current order, entries without an index go after the indexed ones
+	ordered := slices.Clone(d.Entries)
+	slices.SortStableFunc(ordered, func(i, j *Entry) int {
+		return cmp.Compare(unindexedRank(i), unindexedRank(j))
+	})
 
 	// Move Dirs first
 	dirIndex := 1
-	for _, entry := range d.Entries {
+	for _, entry := range ordered {
 		if entry.IsDir && !entry.IsAnchor() {
 			slog.Debug("Moving directory", "entry", entry.String(), "fromIndex", entry.EntryIndex, "toIndex", dirIndex)
 			err := entry.Move(dirIndex)
@@ -345,7 +509,7 @@ func (d *Directory) ApplyNumericIndexing() error {
 
 	// Move Files
 	fileIndex := dirIndex
-	for _, entry := range d.Entries {
+	for _, entry := range ordered {
 		if !entry.IsDir && !entry.IsAnchor() {
 			slog.Debug("Moving file", "entry", entry.String(), "fromIndex", entry.EntryIndex, "toIndex", fileIndex)
 			err := entry.Move(fileIndex)
@@ -356,13 +520,32 @@ func (d *Directory) ApplyNumericIndexing() error {
 		}
 	}
 
+	d.sortEntries()
 	slog.Debug("Completed ApplyNumericIndexing", "path", d.Path)
 	return nil
 }
 
+func unindexedRank(e *Entry) int {
+	if e.EntryIndex == -1 {
+		return 1
+	}
+	return 0
+}
+
+// RepairIndexing renumbers an indexed directory without gaps, keeping directories first and the current order otherwise
+func (d *Directory) RepairIndexing() error {
+	if !d.IsIndexed {
+		return fmt.Errorf("%w: cannot repair indexing in %s: directory is not indexed", ErrIndexConflict, d.Path)
+	}
+	return d.ApplyNumericIndexing()
+}
+
 func (d *Directory) RemoveIndexing() error {
 	slog.Debug("Starting RemoveIndexing", "path", d.Path, "currentEntries", len(d.Entries))
-	d.UpdateIsIndex(false)
+	if err := d.UpdateIsIndex(false); err != nil {
+		return err
+	}
+	d.IsIndexed = false
 	for _, entry := range d.Entries {
 		if !entry.IsAnchor() {
 			slog.Debug("Removing index from entry", "entry", entry.String(), "fromIndex", entry.EntryIndex)
@@ -387,15 +570,15 @@ func (d *Directory) ValidateIndexing() error {
 			}
 
 			// Errors if we run into a directory after flipping foundFirstFile at the first file
-			if !entry.IsDir && !foundFirstFile {
+			if !entry.IsDir {
 				foundFirstFile = true
 			} else if foundFirstFile {
-				return fmt.Errorf("validation failed: found directory after file in %s", d.Path)
+				return fmt.Errorf("%w: found directory %q after file in %s", ErrValidation, entry.Name, d.Path)
 			}
 
 			if entry.EntryIndex != nonAnchorIndex {
-				return fmt.Errorf("index validation failed: entry %q has index %d, expected %d",
-					entry.Name, entry.EntryIndex, nonAnchorIndex)
+				return fmt.Errorf("%w: entry %q has index %d, expected %d",
+					ErrValidation, entry.Name, entry.EntryIndex, nonAnchorIndex)
 			}
 			nonAnchorIndex++
 		}
@@ -0,0 +1,407 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metadata holds a note's frontmatter. Values are strings, []string for lists or Metadata for nested maps.
+type Metadata map[string]any
+
+// String returns a scalar field, lists are joined with ", "
+func (m Metadata) String(key string) string {
+	switch value := m[key].(type) {
+	case string:
+		return value
+	case []string:
+		return strings.Join(value, ", ")
+	}
+	return ""
+}
+
+// Strings returns a list field, a scalar is split on commas
+func (m Metadata) Strings(key string) []string {
+	switch value := m[key].(type) {
+	case []string:
+		return value
+	case string:
+		var values []string
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Date parses a date or date and time field
+func (m Metadata) Date(key string) (time.Time, bool) {
+	value := m.String(key)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339, time.DateTime, "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Tags returns the tags without a leading #, a scalar may separate them with commas or spaces
+func (m Metadata) Tags() []string {
+	var tags []string
+	for _, key := range []string{"tags", "tag"} {
+		values := m.Strings(key)
+		if value, ok := m[key].(string); ok {
+			values = strings.Fields(strings.ReplaceAll(value, ",", " "))
+		}
+		for _, tag := range values {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+			if tag != "" && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// clone copies the metadata along with its lists and nested maps
+func (m Metadata) clone() Metadata {
+	cloned := make(Metadata, len(m))
+	for key, value := range m {
+		switch value := value.(type) {
+		case []string:
+			cloned[key] = slices.Clone(value)
+		case Metadata:
+			cloned[key] = value.clone()
+		default:
+			cloned[key] = value
+		}
+	}
+	return cloned
+}
+
+func (m Metadata) Aliases() []string {
+	return append(m.Strings("aliases"), m.Strings("alias")...)
+}
+
+func (m Metadata) Status() string {
+	return m.String("status")
+}
+
+// Created falls back to the date field, which is what most templates write
+func (m Metadata) Created() (time.Time, bool) {
+	if t, ok := m.Date("created"); ok {
+		return t, true
+	}
+	return m.Date("date")
+}
+
+func (m Metadata) Updated() (time.Time, bool) {
+	if t, ok := m.Date("updated"); ok {
+		return t, true
+	}
+	return m.Date("modified")
+}
+
+// Metadata lazily reads the entry's frontmatter, directories and notes without any have none
+func (e *Entry) Metadata() Metadata {
+	if e.meta != nil {
+		return e.meta
+	}
+
+	e.meta = Metadata{}
+	if e.IsDir || e.Ext != ".md" {
+		return e.meta
+	}
+
+	meta, err := loadMetadata(e.files, e.FilePath())
+	if err != nil {
+		slog.Debug("Failed to read frontmatter", "path", e.FilePath(), "error", err)
+		return e.meta
+	}
+	e.meta = meta
+	return e.meta
+}
+
+type cachedMetadata struct {
+	modTime time.Time
+	size    int64
+	meta    Metadata
+}
+
+// metadataCache keeps parsed frontmatter by absolute path until the file's mtime or size changes.
+// Callers get their own copy so changing it can't leak into other entries.
+var (
+	metadataCache   = map[string]cachedMetadata{}
+	metadataCacheMu sync.Mutex
+)
+
+func loadMetadata(files *FileWriter, path string) (Metadata, error) {
+	if files.IsDryRun() {
+		if data, ok := files.planned.content[path]; ok {
+			return ParseFrontmatter(string(data))
+		}
+		path = files.planned.realPath(path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataCacheMu.Lock()
+	cached, ok := metadataCache[path]
+	metadataCacheMu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.meta.clone(), nil
+	}
+
+	block, err := readFrontmatter(path)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := ParseFrontmatter(block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	metadataCacheMu.Lock()
+	metadataCache[path] = cachedMetadata{info.ModTime(), info.Size(), meta.clone()}
+	metadataCacheMu.Unlock()
+	return meta, nil
+}
+
+// readFrontmatter reads only as far as the end of the frontmatter so large notes stay cheap
+func readFrontmatter(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		lines = append(lines, line)
+		if len(lines) == 1 && strings.TrimSpace(line) != "---" {
+			return "", nil
+		}
+		if len(lines) > 1 && strings.TrimSpace(line) == "---" {
+			return strings.Join(lines, "\n"), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return "", nil
+}
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// ParseFrontmatter reads the leading --- block of a note. It understands the subset of YAML notes use:
+// scalars, quoted strings, [flow] and - block lists, nested maps, | and > block scalars and # comments.
+func ParseFrontmatter(content string) (Metadata, error) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	end := frontmatterEnd(lines)
+	if end == 0 {
+		return Metadata{}, nil
+	}
+
+	var block []yamlLine
+	for i, line := range lines[1 : end-1] {
+		text := strings.TrimLeft(line, " ")
+		block = append(block, yamlLine{number: i + 2, indent: len(line) - len(text), text: strings.TrimRight(text, " \t")})
+	}
+	return parseYAMLMapping(block)
+}
+
+func isBlankYAML(line yamlLine) bool {
+	return line.text == "" || strings.HasPrefix(line.text, "#")
+}
+
+func isYAMLListItem(line yamlLine) bool {
+	return line.text == "-" || strings.HasPrefix(line.text, "- ")
+}
+
+func parseYAMLMapping(lines []yamlLine) (Metadata, error) {
+	meta := Metadata{}
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlankYAML(line) {
+			i++
+			continue
+		}
+
+		key, value, ok := cutYAMLKey(line.text)
+		if !ok || isYAMLListItem(line) {
+			return nil, fmt.Errorf("%w: frontmatter line %d: expected key: value, got %q", ErrValidation, line.number, line.text)
+		}
+
+		// Everything indented further belongs to this key, YAML also allows a list at the key's own indent
+		end := i + 1
+		for end < len(lines) && (isBlankYAML(lines[end]) || lines[end].indent > line.indent ||
+			(lines[end].indent == line.indent && isYAMLListItem(lines[end]))) {
+			end++
+		}
+
+		parsed, err := parseYAMLValue(strings.TrimSpace(value), lines[i+1:end])
+		if err != nil {
+			return nil, err
+		}
+		meta[unquoteYAML(strings.TrimSpace(key))] = parsed
+		i = end
+	}
+	return meta, nil
+}
+
+// cutYAMLKey splits key: value, a quoted key may contain colons itself
+func cutYAMLKey(text string) (key string, value string, ok bool) {
+	start := 0
+	if text != "" && (text[0] == '"' || text[0] == '\'') {
+		if end := strings.IndexByte(text[1:], text[0]); end >= 0 {
+			start = end + 2
+		}
+	}
+	i := strings.Index(text[start:], ":")
+	if i < 0 {
+		return "", "", false
+	}
+	return text[:start+i], text[start+i+1:], true
+}
+
+func parseYAMLValue(value string, children []yamlLine) (any, error) {
+	if value != "" && strings.ContainsAny(value[:1], "|>") {
+		return parseYAMLBlockScalar(value, children), nil
+	}
+	if value != "" {
+		if strings.HasPrefix(value, "[") {
+			return parseYAMLFlowList(value), nil
+		}
+		return parseYAMLScalar(value), nil
+	}
+
+	var content []yamlLine
+	for _, child := range children {
+		if !isBlankYAML(child) {
+			content = append(content, child)
+		}
+	}
+	if len(content) == 0 {
+		return "", nil
+	}
+	if !isYAMLListItem(content[0]) {
+		return parseYAMLMapping(content)
+	}
+
+	items := []string{}
+	for _, child := range content {
+		if child.indent != content[0].indent || !isYAMLListItem(child) {
+			continue
+		}
+		items = append(items, parseYAMLScalar(strings.TrimSpace(strings.TrimPrefix(child.text, "-"))))
+	}
+	return items, nil
+}
+
+// parseYAMLBlockScalar keeps the lines of a | block and folds those of a > block into one, trailing newlines are dropped
+func parseYAMLBlockScalar(indicator string, children []yamlLine) string {
+	baseIndent := -1
+	for _, child := range children {
+		if child.text != "" && (baseIndent == -1 || child.indent < baseIndent) {
+			baseIndent = child.indent
+		}
+	}
+
+	var lines []string
+	for _, child := range children {
+		if child.text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, strings.Repeat(" ", child.indent-baseIndent)+child.text)
+	}
+
+	text := strings.Join(lines, "\n")
+	if indicator[0] == '>' {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	return strings.TrimRight(text, "\n")
+}
+
+func parseYAMLFlowList(value string) []string {
+	inner := strings.TrimPrefix(value, "[")
+	if end := strings.LastIndex(inner, "]"); end >= 0 {
+		inner = inner[:end]
+	}
+
+	items := []string{}
+	var current strings.Builder
+	var quote rune
+	for _, r := range inner {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			if item := parseYAMLScalar(strings.TrimSpace(current.String())); item != "" {
+				items = append(items, item)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if item := parseYAMLScalar(strings.TrimSpace(current.String())); item != "" {
+		items = append(items, item)
+	}
+	return items
+}
+
+func parseYAMLScalar(value string) string {
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+		return unquoteYAML(value)
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	if value == "~" || value == "null" {
+		return ""
+	}
+	return value
+}
+
+// unquoteYAML strips matching quotes, handling the escapes each style allows
+func unquoteYAML(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	quote := value[0]
+	end := strings.LastIndexByte(value, quote)
+	if (quote != '"' && quote != '\'') || end == 0 {
+		return value
+	}
+
+	inner := value[1:end]
+	if quote == '\'' {
+		return strings.ReplaceAll(inner, "''", "'")
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\n`, "\n", `\t`, "\t").Replace(inner)
+}
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFrontmatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Metadata
+		wantErr error
+	}{
+		{
+			name:    "no frontmatter",
+			content: "# Note\n\nstatus: draft\n",
+			want:    Metadata{},
+		},
+		{
+			name:    "unclosed frontmatter",
+			content: "---\nstatus: draft\n",
+			want:    Metadata{},
+		},
+		{
+			name:    "scalars",
+			content: "---\nstatus: draft\ncreated: 2026-10-19\nempty:\nnothing: ~\n---\n# Note\n",
+			want:    Metadata{"status": "draft", "created": "2026-10-19", "empty": "", "nothing": ""},
+		},
+		{
+			name:    "quoted values",
+			content: "---\ntitle: \"Hello: \\\"world\\\"\"\nnote: 'it''s # not a comment'\n---\n",
+			want:    Metadata{"title": `Hello: "world"`, "note": "it's # not a comment"},
+		},
+		{
+			name:    "quoted keys",
+			content: "---\n\"time: spent\": 2h\n'plain': yes\n---\n",
+			want:    Metadata{"time: spent": "2h", "plain": "yes"},
+		},
+		{
+			name:    "comments",
+			content: "---\n# owned by the template\nstatus: draft # for now\n\nrating: 3\n---\n",
+			want:    Metadata{"status": "draft", "rating": "3"},
+		},
+		{
+			name:    "flow list",
+			content: "---\ntags: [garden, \"a, b\", 'c']\nempty: []\n---\n",
+			want:    Metadata{"tags": []string{"garden", "a, b", "c"}, "empty": []string{}},
+		},
+		{
+			name:    "block list",
+			content: "---\naliases:\n  - Garden\n  - \"The Log\"\ntags:\n- at key indent\n---\n",
+			want:    Metadata{"aliases": []string{"Garden", "The Log"}, "tags": []string{"at key indent"}},
+		},
+		{
+			name:    "nested map",
+			content: "---\nproject:\n  name: Garden\n  links:\n    - one\n---\n",
+			want:    Metadata{"project": Metadata{"name": "Garden", "links": []string{"one"}}},
+		},
+		{
+			name:    "literal block",
+			content: "---\nsummary: |\n  first\n    indented\n\n  last\nstatus: done\n---\n",
+			want:    Metadata{"summary": "first\n  indented\n\nlast", "status": "done"},
+		},
+		{
+			name:    "folded block",
+			content: "---\nsummary: >\n  one\n  two\n---\n",
+			want:    Metadata{"summary": "one two"},
+		},
+		{
+			name:    "windows line endings",
+			content: "---\r\nstatus: draft\r\n---\r\n",
+			want:    Metadata{"status": "draft"},
+		},
+		{
+			name:    "line without key",
+			content: "---\nstatus: draft\njust text\n---\n",
+			wantErr: ErrValidation,
+		},
+		{
+			name:    "list without key",
+			content: "---\n- loose\n---\n",
+			wantErr: ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFrontmatter(tt.content)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseFrontmatter() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFrontmatter() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFrontmatter() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "comma scalar",
+			content: "---\ntags: garden, area/health,notes\n---\n",
+			want:    []string{"garden", "area/health", "notes"},
+		},
+		{
+			name:    "space scalar",
+			content: "---\ntags: garden area/health  notes\n---\n",
+			want:    []string{"garden", "area/health", "notes"},
+		},
+		{
+			name:    "hash prefixes",
+			content: "---\ntags: #garden,#notes\n---\n",
+			want:    []string{"garden", "notes"},
+		},
+		{
+			name:    "hash after space starts a comment",
+			content: "---\ntags: garden #notes\n---\n",
+			want:    []string{"garden"},
+		},
+		{
+			name:    "flow list",
+			content: "---\ntags: [garden, \"#area/health\"]\n---\n",
+			want:    []string{"garden", "area/health"},
+		},
+		{
+			name:    "block list",
+			content: "---\ntags:\n  - garden\n  - '#area/health'\n---\n",
+			want:    []string{"garden", "area/health"},
+		},
+		{
+			name:    "tag key",
+			content: "---\ntag: garden\n---\n",
+			want:    []string{"garden"},
+		},
+		{
+			name:    "both keys deduplicated",
+			content: "---\ntags: [garden, notes]\ntag: garden\n---\n",
+			want:    []string{"garden", "notes"},
+		},
+		{
+			name:    "quoted key",
+			content: "---\n\"tags\": [garden]\n---\n",
+			want:    []string{"garden"},
+		},
+		{
+			name:    "none",
+			content: "---\nstatus: draft\n---\n",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := ParseFrontmatter(tt.content)
+			if err != nil {
+				t.Fatalf("ParseFrontmatter() error = %v", err)
+			}
+			if got := meta.Tags(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tags() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type LinkProblem string
+
+const (
+	// LinkStale points to a note that still exists under a different index
+	LinkStale LinkProblem = "stale"
+	// LinkMissing points to nothing in the garden
+	LinkMissing LinkProblem = "missing"
+)
+
+// BrokenLink is a link that doesn't resolve as written. Stale links carry the note they meant in Path
+// and the link rewritten to its current name in Fix.
+type BrokenLink struct {
+	NoteLink
+	Problem LinkProblem
+	Fix     string
+}
+
+// CheckLinks finds every wikilink and markdown link in the garden that doesn't resolve as written.
+// Wikilinks without indexes match any index, so only links that spell out an index can go stale.
+func (s *EntryService) CheckLinks() ([]BrokenLink, error) {
+	docs, err := s.indexedDocs()
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := s.newLinkResolver(docs)
+	var broken []BrokenLink
+	for _, source := range slices.Sorted(maps.Keys(docs)) {
+		for _, link := range docs[source].Links {
+			noteLink := NoteLink{Link: link, Source: source, Path: resolver.resolve(source, link)}
+
+			var problem *BrokenLink
+			if link.Kind == LinkWiki {
+				problem = checkWikiLink(noteLink)
+			} else {
+				problem, err = s.checkMarkdownLink(noteLink)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if problem != nil {
+				broken = append(broken, *problem)
+			}
+		}
+	}
+
+	slog.Debug("Checked links", "notes", len(docs), "broken", len(broken))
+	return broken, nil
+}
+
+// checkWikiLink compares every component of the target that spells out an index with the note the link resolves to
+func checkWikiLink(link NoteLink) *BrokenLink {
+	if link.Path == "" {
+		return &BrokenLink{NoteLink: link, Problem: LinkMissing}
+	}
+
+	written := strings.Split(link.Target, "/")
+	actual := strings.Split(filepath.ToSlash(link.Path), "/")
+	if len(actual) < len(written) {
+		return nil
+	}
+	actual = actual[len(actual)-len(written):]
+
+	stale := false
+	for i, component := range written {
+		if !indexPrefixPattern.MatchString(component) {
+			continue
+		}
+		current := actual[i]
+		if !strings.HasSuffix(component, ".md") {
+			current = strings.TrimSuffix(current, ".md")
+		}
+		if !strings.EqualFold(component, current) {
+			written[i] = current
+			stale = true
+		}
+	}
+	if !stale {
+		return nil
+	}
+
+	fix := strings.Replace(link.Raw, link.Target, strings.Join(written, "/"), 1)
+	return &BrokenLink{NoteLink: link, Problem: LinkStale, Fix: fix}
+}
+
+// checkMarkdownLink looks a relative link that doesn't resolve up again regardless of index
+func (s *EntryService) checkMarkdownLink(link NoteLink) (*BrokenLink, error) {
+	if link.Path != "" {
+		return nil, nil
+	}
+
+	targetPath := filepath.Join(filepath.Dir(link.Source), link.Target)
+	if strings.HasPrefix(link.Target, "/") {
+		targetPath = filepath.Clean(strings.TrimPrefix(link.Target, "/"))
+	}
+	if targetPath == ".." || strings.HasPrefix(targetPath, "../") {
+		return &BrokenLink{NoteLink: link, Problem: LinkMissing}, nil
+	}
+
+	current, found, err := s.FindPath(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	if !found || current == "" {
+		return &BrokenLink{NoteLink: link, Problem: LinkMissing}, nil
+	}
+
+	link.Path = current
+	return &BrokenLink{NoteLink: link, Problem: LinkStale, Fix: rewriteMarkdownLink(link)}, nil
+}
+
+// rewriteMarkdownLink points the link at its current path, keeping its text, heading and escaping style
+func rewriteMarkdownLink(link NoteLink) string {
+	match := markdownLinkPattern.FindStringSubmatchIndex(link.Raw)
+	href := link.Raw[match[4]:match[5]]
+
+	newPath, err := filepath.Rel(filepath.Dir(link.Source), link.Path)
+	if err != nil || strings.HasPrefix(link.Target, "/") {
+		newPath = "/" + link.Path
+	}
+	newPath = filepath.ToSlash(newPath)
+
+	bracketed := strings.HasPrefix(href, "<")
+	if !bracketed && (strings.Contains(href, "%") || strings.Contains(newPath, " ")) {
+		components := strings.Split(newPath, "/")
+		for i, component := range components {
+			components[i] = url.PathEscape(component)
+		}
+		newPath = strings.Join(components, "/")
+	}
+	if link.Heading != "" {
+		newPath += "#" + link.Heading
+	}
+	if bracketed {
+		newPath = "<" + newPath + ">"
+	}
+
+	return link.Raw[:match[4]] + newPath + link.Raw[match[5]:]
+}
+
+// FixLinks rewrites every stale link to its current name, one write per note, and returns how many it fixed
+func (s *EntryService) FixLinks(broken []BrokenLink) (int, error) {
+	bySource := map[string][]BrokenLink{}
+	for _, link := range broken {
+		if link.Problem == LinkStale {
+			bySource[link.Source] = append(bySource[link.Source], link)
+		}
+	}
+
+	fixed := 0
+	for _, source := range slices.Sorted(maps.Keys(bySource)) {
+		absPath := filepath.Join(s.config.RootDir, source)
+		content, err := s.files.readFile(absPath)
+		if err != nil {
+			return fixed, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+
+		// Each broken link is one occurrence, identical links on a line are replaced one at a time
+		lines := strings.Split(string(content), "\n")
+		changed := false
+		for _, link := range bySource[source] {
+			if link.Line > len(lines) || !strings.Contains(lines[link.Line-1], link.Raw) {
+				slog.Debug("Link moved since it was checked, skipping it", "path", source, "link", link.Raw)
+				continue
+			}
+			lines[link.Line-1] = strings.Replace(lines[link.Line-1], link.Raw, link.Fix, 1)
+			changed = true
+			fixed++
+		}
+		if !changed {
+			continue
+		}
+
+		if err := s.files.writeAtomic(absPath, []byte(strings.Join(lines, "\n"))); err != nil {
+			return fixed, fmt.Errorf("failed to rewrite links in %s: %w", source, err)
+		}
+	}
+	return fixed, nil
+}
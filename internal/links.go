@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type LinkKind string
+
+const (
+	LinkWiki     LinkKind = "wiki"
+	LinkMarkdown LinkKind = "markdown"
+)
+
+var (
+	markdownLinkPattern = regexp.MustCompile(`\[([^\]]*)\]\((<[^>]+>|[^)\s]+)(?:\s+"[^"]*")?\)`)
+	wikiLinkPattern     = regexp.MustCompile(`\[\[([^\]|#]*)(?:#([^\]|]*))?(?:\|([^\]]*))?\]\]`)
+)
+
+// Link is a link as it's written in a note. Target is the wikilink name or the unescaped markdown path,
+// Text the wikilink alias or the markdown link text and Raw the whole link so it can be rewritten.
+type Link struct {
+	Kind    LinkKind `json:"kind"`
+	Target  string   `json:"target"`
+	Heading string   `json:"heading,omitempty"`
+	Text    string   `json:"text,omitempty"`
+	Line    int      `json:"line"`
+	Raw     string   `json:"raw"`
+}
+
+// NoteLink is a link from the note at Source, Path is the note it resolves to or "" when nothing matches
+type NoteLink struct {
+	Link
+	Source string
+	Path   string
+}
+
+// ParseLinks returns the wikilinks and markdown links to other files in content, skipping code and external URLs
+func ParseLinks(content string) []Link {
+	var links []Link
+	proseLines(content, func(number int, line string) {
+		for _, match := range wikiLinkPattern.FindAllStringSubmatch(line, -1) {
+			target := strings.TrimSpace(match[1])
+			// [[#Heading]] points into the note itself
+			if target == "" {
+				continue
+			}
+			links = append(links, Link{
+				Kind:    LinkWiki,
+				Target:  target,
+				Heading: strings.TrimSpace(match[2]),
+				Text:    strings.TrimSpace(match[3]),
+				Line:    number + 1,
+				Raw:     match[0],
+			})
+		}
+
+		for _, match := range markdownLinkPattern.FindAllStringSubmatch(line, -1) {
+			target := strings.TrimSuffix(strings.TrimPrefix(match[2], "<"), ">")
+			if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+				continue
+			}
+			target, heading, _ := strings.Cut(target, "#")
+			if target == "" {
+				continue
+			}
+			if unescaped, err := url.PathUnescape(target); err == nil {
+				target = unescaped
+			}
+			links = append(links, Link{
+				Kind:    LinkMarkdown,
+				Target:  target,
+				Heading: heading,
+				Text:    match[1],
+				Line:    number + 1,
+				Raw:     match[0],
+			})
+		}
+	})
+	return links
+}
+
+// linkResolver finds the notes links point to, wikilinks by name or alias ignoring indexes and case
+type linkResolver struct {
+	root  string
+	files *FileWriter
+	names map[string][]string
+	notes map[string]bool
+}
+
+func (s *EntryService) newLinkResolver(docs map[string]*indexedDoc) *linkResolver {
+	r := &linkResolver{root: s.config.RootDir, files: s.files, names: map[string][]string{}, notes: map[string]bool{}}
+	for _, notePath := range slices.Sorted(maps.Keys(docs)) {
+		r.notes[notePath] = true
+		r.names[normalizeName(filepath.Base(notePath))] = append(r.names[normalizeName(filepath.Base(notePath))], notePath)
+		for _, alias := range docs[notePath].Aliases {
+			r.names[normalizeName(alias)] = append(r.names[normalizeName(alias)], notePath)
+		}
+	}
+	return r
+}
+
+// normalizeLinkPath normalizes every component of a wikilink target or note path so they can be compared
+func normalizeLinkPath(linkPath string) string {
+	components := strings.Split(filepath.ToSlash(linkPath), "/")
+	for i, component := range components {
+		components[i] = normalizeName(component)
+	}
+	return strings.Join(components, "/")
+}
+
+func (r *linkResolver) resolve(source string, link Link) string {
+	if link.Kind == LinkMarkdown {
+		targetPath := filepath.Join(filepath.Dir(source), link.Target)
+		if strings.HasPrefix(link.Target, "/") {
+			targetPath = filepath.Clean(strings.TrimPrefix(link.Target, "/"))
+		}
+		if targetPath == ".." || strings.HasPrefix(targetPath, "../") {
+			return ""
+		}
+		if r.notes[targetPath] || r.files.exists(filepath.Join(r.root, targetPath)) {
+			return targetPath
+		}
+		return ""
+	}
+
+	target := normalizeLinkPath(link.Target)
+	var candidates []string
+	for _, candidate := range r.names[filepath.Base(target)] {
+		normalized := normalizeLinkPath(candidate)
+		if !strings.Contains(target, "/") || normalized == target || strings.HasSuffix(normalized, "/"+target) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	// Like most editors, prefer a note next to the source, then the one closest to the root
+	for _, candidate := range candidates {
+		if filepath.Dir(candidate) == filepath.Dir(source) {
+			return candidate
+		}
+	}
+	return slices.MinFunc(candidates, func(a, b string) int {
+		return cmp.Or(cmp.Compare(strings.Count(a, "/"), strings.Count(b, "/")), cmp.Compare(a, b))
+	})
+}
+
+// NoteLinks returns the links from other notes to notePath and the links going out of it
+func (s *EntryService) NoteLinks(notePath string) (backlinks []NoteLink, outgoing []NoteLink, err error) {
+	docs, err := s.indexedDocs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc, ok := docs[notePath]
+	if !ok {
+		// Ignored notes aren't indexed but can still link elsewhere
+		content, err := s.files.readFile(filepath.Join(s.config.RootDir, notePath))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", notePath, err)
+		}
+		doc = &indexedDoc{}
+		annotateDoc(doc, notePath, content)
+	}
+
+	resolver := s.newLinkResolver(docs)
+	for _, link := range doc.Links {
+		outgoing = append(outgoing, NoteLink{Link: link, Source: notePath, Path: resolver.resolve(notePath, link)})
+	}
+
+	for _, source := range slices.Sorted(maps.Keys(docs)) {
+		if source == notePath {
+			continue
+		}
+		for _, link := range docs[source].Links {
+			if resolver.resolve(source, link) == notePath {
+				backlinks = append(backlinks, NoteLink{Link: link, Source: source, Path: notePath})
+			}
+		}
+	}
+	return backlinks, outgoing, nil
+}
@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckWikiLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		target  string
+		path    string
+		want    LinkProblem
+		wantFix string
+	}{
+		{name: "missing", raw: "[[Gone]]", target: "Gone", want: LinkMissing},
+		{name: "no index written", raw: "[[Plan]]", target: "Plan", path: "Projects/03. Plan.md"},
+		{name: "current index", raw: "[[03. plan]]", target: "03. plan", path: "Projects/03. Plan.md"},
+		{
+			name: "old index", raw: "[[02. Plan]]", target: "02. Plan", path: "Projects/03. Plan.md",
+			want: LinkStale, wantFix: "[[03. Plan]]",
+		},
+		{
+			name: "old index with extension", raw: "[[02. Plan.md#Goals]]", target: "02. Plan.md", path: "03. Plan.md",
+			want: LinkStale, wantFix: "[[03. Plan.md#Goals]]",
+		},
+		{
+			name: "old directory index keeps the alias", raw: "[[02. Projects/Plan|the plan]]", target: "02. Projects/Plan",
+			path: "03. Projects/Plan.md", want: LinkStale, wantFix: "[[03. Projects/Plan|the plan]]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := NoteLink{Link: Link{Kind: LinkWiki, Target: tt.target, Raw: tt.raw}, Path: tt.path}
+			got := checkWikiLink(link)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("checkWikiLink() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Problem != tt.want || got.Fix != tt.wantFix {
+				t.Errorf("checkWikiLink() = %+v, want %s with fix %q", got, tt.want, tt.wantFix)
+			}
+		})
+	}
+}
+
+func TestRewriteMarkdownLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		raw     string
+		target  string
+		heading string
+		path    string
+		want    string
+	}{
+		{
+			name: "same directory", source: "old.md", raw: "[x](a.md)", target: "a.md", path: "b.md",
+			want: "[x](b.md)",
+		},
+		{
+			name: "escaped spaces", source: "Projects/a.md", raw: "[Plan](02.%20Plan.md)", target: "02. Plan.md",
+			path: "Projects/03. Plan.md", want: "[Plan](03.%20Plan.md)",
+		},
+		{
+			name: "angle brackets", source: "Projects/a.md", raw: "[Plan](<02. Plan.md>)", target: "02. Plan.md",
+			path: "Projects/03. Plan.md", want: "[Plan](<03. Plan.md>)",
+		},
+		{
+			name: "other directory with heading and title", source: "Inbox/a.md",
+			raw: `[x](../Projects/02.%20Plan.md#Goals "title")`, target: "../Projects/02. Plan.md", heading: "Goals",
+			path: "Projects/03. Plan.md", want: `[x](../Projects/03.%20Plan.md#Goals "title")`,
+		},
+		{
+			name: "root relative", source: "Inbox/a.md", raw: "[x](/Projects/02.%20Plan.md)", target: "/Projects/02. Plan.md",
+			path: "Projects/03. Plan.md", want: "[x](/Projects/03.%20Plan.md)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := NoteLink{
+				Link:   Link{Kind: LinkMarkdown, Target: tt.target, Heading: tt.heading, Raw: tt.raw},
+				Source: tt.source,
+				Path:   tt.path,
+			}
+			if got := rewriteMarkdownLink(link); got != tt.want {
+				t.Errorf("rewriteMarkdownLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixLinks(t *testing.T) {
+	s := newTestService(t, ".index", "01. Projects/.index", "01. Projects/02. Plan.md")
+	writeNote(t, s, "01. Projects/01. Log.md",
+		"[[01. Plan]] and [[01. Plan]]\n[p](01.%20Plan.md)\n[[Gone]] [[Plan]]\n```\n[[01. Plan]]\n```\n")
+
+	broken, err := s.CheckLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed, err := s.FixLinks(broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed != 3 {
+		t.Errorf("FixLinks() fixed %d links, want 3", fixed)
+	}
+
+	content, err := os.ReadFile(filepath.Join(s.config.RootDir, "01. Projects/01. Log.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[[02. Plan]] and [[02. Plan]]\n[p](02.%20Plan.md)\n[[Gone]] [[Plan]]\n```\n[[01. Plan]]\n```\n"
+	if string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+
+	// The rewrite keeps the size and can land in the same clock tick, move it on so the search index rereads it
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(filepath.Join(s.config.RootDir, "01. Projects/01. Log.md"), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the missing link is left, a note without stale links isn't rewritten
+	broken, err = s.CheckLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(broken) != 1 || broken[0].Problem != LinkMissing || broken[0].Target != "Gone" {
+		t.Errorf("CheckLinks() after fixing = %+v, want only the missing link", broken)
+	}
+	if fixed, err := s.FixLinks(broken); err != nil || fixed != 0 {
+		t.Errorf("FixLinks() of missing links = %d, %v, want nothing fixed", fixed, err)
+	}
+	if info, err := os.Stat(filepath.Join(s.config.RootDir, "01. Projects/01. Log.md")); err != nil || !info.ModTime().Equal(later) {
+		t.Errorf("a note without stale links was rewritten")
+	}
+}
+
+func TestFixLinksDryRun(t *testing.T) {
+	s := newTestService(t, ".index", "02. Plan.md")
+	writeNote(t, s, "log.md", "[[01. Plan]]\n")
+	s.SetWriteMode(WriteDryRun)
+
+	broken, err := s.CheckLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed, err := s.FixLinks(broken); err != nil || fixed != 1 {
+		t.Fatalf("FixLinks() = %d, %v, want 1 fixed", fixed, err)
+	}
+	if changes := s.TakeChanges(); len(changes) != 1 {
+		t.Errorf("recorded %d changes, want 1", len(changes))
+	}
+	if content, _ := os.ReadFile(filepath.Join(s.config.RootDir, "log.md")); string(content) != "[[01. Plan]]\n" {
+		t.Errorf("dry run rewrote the note to %q", content)
+	}
+}
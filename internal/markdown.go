@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+type Heading struct {
+	Level int
+	Text  string
+	Line  int
+}
+
+// ParseHeadings returns the ATX headings in lines, skipping frontmatter and fenced code blocks
+func ParseHeadings(lines []string) []Heading {
+	var headings []Heading
+	inFence := false
+	for i := frontmatterEnd(lines); i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		matches := headingPattern.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		headings = append(headings, Heading{Level: len(matches[1]), Text: matches[2], Line: i})
+	}
+	return headings
+}
+
+// parseHeadingArg splits "## Log" into its level and text, plain text defaults to level 2
+func parseHeadingArg(heading string) (int, string) {
+	if matches := headingPattern.FindStringSubmatch(heading); matches != nil {
+		return len(matches[1]), matches[2]
+	}
+	return 2, strings.TrimSpace(heading)
+}
+
+// AppendBlock adds block to the end of content, making sure it starts on its own line
+func AppendBlock(content string, block string) string {
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + strings.TrimRight(block, "\n") + "\n"
+}
+
+type InsertPosition int
+
+const (
+	PositionBottom InsertPosition = iota
+	PositionTop
+)
+
+func ParseInsertPosition(value string) (InsertPosition, error) {
+	switch value {
+	case "bottom", "":
+		return PositionBottom, nil
+	case "top":
+		return PositionTop, nil
+	default:
+		return PositionBottom, fmt.Errorf("%w: unknown position %q (expected top or bottom)", ErrValidation, value)
+	}
+}
+
+// InsertBlock adds block to the note body at position, top means right after any frontmatter and title
+func InsertBlock(content string, block string, position InsertPosition) string {
+	if position == PositionBottom {
+		return AppendBlock(content, block)
+	}
+
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	bodyStart := frontmatterEnd(lines)
+	headings := ParseHeadings(lines)
+	if len(headings) > 0 && headings[0].Level == 1 && headings[0].Line == nextContentLine(lines, bodyStart) {
+		bodyStart = headings[0].Line + 1
+	}
+	return insertLines(lines, bodyStart, len(lines), splitBlock(block), position)
+}
+
+// InsertUnderHeading adds block to the section under heading, creating the heading at the end if it is missing.
+// The heading may carry its level ("## Log") to only match headings of that level.
+func InsertUnderHeading(content string, heading string, block string, position InsertPosition) string {
+	level, text := parseHeadingArg(heading)
+	explicitLevel := strings.HasPrefix(strings.TrimSpace(heading), "#")
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+
+	headings := ParseHeadings(lines)
+	for i, h := range headings {
+		if !strings.EqualFold(h.Text, text) || (explicitLevel && h.Level != level) {
+			continue
+		}
+
+		// The section's own content ends at the next heading, nested sections keep their content
+		sectionEnd := len(lines)
+		if i+1 < len(headings) {
+			sectionEnd = headings[i+1].Line
+		}
+		return insertLines(lines, h.Line+1, sectionEnd, splitBlock(block), position)
+	}
+
+	newSection := strings.Repeat("#", level) + " " + text + "\n\n" + strings.Join(splitBlock(block), "\n")
+	if strings.TrimSpace(content) == "" {
+		return newSection + "\n"
+	}
+	return strings.Join(lines, "\n") + "\n\n" + newSection + "\n"
+}
+
+// insertLines places block at the top or bottom of the content in lines[start:end].
+// Blank lines around the section are normalised to one, list items stay in one contiguous list.
+func insertLines(lines []string, start int, end int, block []string, position InsertPosition) string {
+	first := nextContentLine(lines[:end], start)
+	last := end
+	for last > first && strings.TrimSpace(lines[last-1]) == "" {
+		last--
+	}
+	section := lines[first:last]
+
+	var body []string
+	if position == PositionTop {
+		body = append(body, block...)
+		if len(section) > 0 && !(isListItem(block[len(block)-1]) && isListItem(section[0])) {
+			body = append(body, "")
+		}
+		body = append(body, section...)
+	} else {
+		body = append(body, section...)
+		if len(section) > 0 && !(isListItem(section[len(section)-1]) && isListItem(block[0])) {
+			body = append(body, "")
+		}
+		body = append(body, block...)
+	}
+
+	result := append([]string{}, lines[:start]...)
+	if start > 0 {
+		result = append(result, "")
+	}
+	result = append(result, body...)
+	if end < len(lines) {
+		result = append(result, "")
+	}
+	result = append(result, lines[end:]...)
+	return strings.Join(result, "\n") + "\n"
+}
+
+// nextContentLine returns the first non-blank line at or after start, or len(lines)
+func nextContentLine(lines []string, start int) int {
+	for i := start; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// frontmatterEnd returns the line after a leading --- frontmatter block, or 0 without one
+func frontmatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+var inlineCodePattern = regexp.MustCompile("`[^`]*`")
+
+// proseLines calls fn with the 0-based number and text of every line outside frontmatter and fenced code.
+// Inline code is blanked out with spaces so offsets into the text still match the original line.
+func proseLines(content string, fn func(number int, text string)) {
+	lines := strings.Split(content, "\n")
+	inFence := false
+	for i := frontmatterEnd(lines); i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		fn(i, inlineCodePattern.ReplaceAllStringFunc(lines[i], func(code string) string {
+			return strings.Repeat(" ", len(code))
+		}))
+	}
+}
+
+func isListItem(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "+ ")
+}
+
+func splitBlock(block string) []string {
+	return strings.Split(strings.TrimRight(block, "\n"), "\n")
+}
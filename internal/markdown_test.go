@@ -0,0 +1,149 @@
+package internal
+
+import "testing"
+
+func TestInsertUnderHeading(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		heading  string
+		block    string
+		position InsertPosition
+		want     string
+	}{
+		{
+			name:     "bottom of section",
+			content:  "# Day\n\n## Log\n\nfirst\n\n## Notes\n\nkeep\n",
+			heading:  "Log",
+			block:    "second",
+			position: PositionBottom,
+			want:     "# Day\n\n## Log\n\nfirst\n\nsecond\n\n## Notes\n\nkeep\n",
+		},
+		{
+			name:     "top of section",
+			content:  "# Day\n\n## Log\n\nfirst\n\n## Notes\n",
+			heading:  "Log",
+			block:    "zeroth",
+			position: PositionTop,
+			want:     "# Day\n\n## Log\n\nzeroth\n\nfirst\n\n## Notes\n",
+		},
+		{
+			name:     "list items stay one list",
+			content:  "## Tasks\n\n- one\n- two\n\n\n## Notes\n",
+			heading:  "tasks",
+			block:    "- three",
+			position: PositionBottom,
+			want:     "## Tasks\n\n- one\n- two\n- three\n\n## Notes\n",
+		},
+		{
+			name:     "empty section",
+			content:  "## Log\n## Notes\n",
+			heading:  "Log",
+			block:    "first",
+			position: PositionBottom,
+			want:     "## Log\n\nfirst\n\n## Notes\n",
+		},
+		{
+			name:     "nested section keeps its content",
+			content:  "## Log\n\nfirst\n\n### Detail\n\nnested\n",
+			heading:  "Log",
+			block:    "second",
+			position: PositionBottom,
+			want:     "## Log\n\nfirst\n\nsecond\n\n### Detail\n\nnested\n",
+		},
+		{
+			name:     "explicit level skips other levels",
+			content:  "# Log\n\nintro\n\n## Log\n\nfirst\n",
+			heading:  "## Log",
+			block:    "second",
+			position: PositionBottom,
+			want:     "# Log\n\nintro\n\n## Log\n\nfirst\n\nsecond\n",
+		},
+		{
+			name:     "missing heading is appended",
+			content:  "# Day\n\ntext\n",
+			heading:  "### Log",
+			block:    "first",
+			position: PositionTop,
+			want:     "# Day\n\ntext\n\n### Log\n\nfirst\n",
+		},
+		{
+			name:     "empty note",
+			content:  "",
+			heading:  "Log",
+			block:    "first",
+			position: PositionBottom,
+			want:     "## Log\n\nfirst\n",
+		},
+		{
+			name:     "headings in code fences are ignored",
+			content:  "```\n## Log\n```\n",
+			heading:  "Log",
+			block:    "first",
+			position: PositionBottom,
+			want:     "```\n## Log\n```\n\n## Log\n\nfirst\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InsertUnderHeading(tt.content, tt.heading, tt.block, tt.position); got != tt.want {
+				t.Errorf("InsertUnderHeading() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsertBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		block    string
+		position InsertPosition
+		want     string
+	}{
+		{
+			name:     "bottom",
+			content:  "# Day\n\ntext",
+			block:    "more\n",
+			position: PositionBottom,
+			want:     "# Day\n\ntext\nmore\n",
+		},
+		{
+			name:     "top after title",
+			content:  "# Day\n\ntext\n",
+			block:    "first",
+			position: PositionTop,
+			want:     "# Day\n\nfirst\n\ntext\n",
+		},
+		{
+			name:     "top after frontmatter and title",
+			content:  "---\nstatus: draft\n---\n# Day\n\n- one\n",
+			block:    "- zero",
+			position: PositionTop,
+			want:     "---\nstatus: draft\n---\n# Day\n\n- zero\n- one\n",
+		},
+		{
+			name:     "top without title",
+			content:  "text\n",
+			block:    "first",
+			position: PositionTop,
+			want:     "first\n\ntext\n",
+		},
+		{
+			name:     "top keeps a later title in place",
+			content:  "intro\n\n# Day\n",
+			block:    "first",
+			position: PositionTop,
+			want:     "first\n\nintro\n\n# Day\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InsertBlock(tt.content, tt.block, tt.position); got != tt.want {
+				t.Errorf("InsertBlock() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
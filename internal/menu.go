@@ -1,5 +1,11 @@
 package internal
 
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
 type Mode int
 
 const (
@@ -9,6 +15,15 @@ const (
 	ModeNewDirectory
 	ModePickTemplate
 	ModeSettings
+	ModePeriodic
+	ModeCapture
+	ModeSearch
+	ModeSearchResults
+	ModeQuickSwitch
+	ModeRecent
+	ModeTags
+	ModeTagged
+	ModeLinks
 )
 
 func (mode Mode) String() string {
@@ -25,6 +40,24 @@ func (mode Mode) String() string {
 		return "ModePickTemplate"
 	case ModeSettings:
 		return "ModeSettings"
+	case ModePeriodic:
+		return "ModePeriodic"
+	case ModeCapture:
+		return "ModeCapture"
+	case ModeSearch:
+		return "ModeSearch"
+	case ModeSearchResults:
+		return "ModeSearchResults"
+	case ModeQuickSwitch:
+		return "ModeQuickSwitch"
+	case ModeRecent:
+		return "ModeRecent"
+	case ModeTags:
+		return "ModeTags"
+	case ModeTagged:
+		return "ModeTagged"
+	case ModeLinks:
+		return "ModeLinks"
 	default:
 		return ""
 	}
@@ -36,6 +69,14 @@ type MenuState struct {
 	config    *Config
 	nav       *Navigator
 	notes     *EntryService
+	result    *Result
+	hits      []SearchHit
+	notePaths []string
+	pins      []Pin
+	tags      []TagCount
+	tag       string
+	linkNote  string
+	links     []linkMenuItem
 }
 
 // func (m *MenuState) formatStatusMessage() string {
@@ -43,24 +84,49 @@ type MenuState struct {
 // 	return fmt.Sprintf("Path: %s \nIndexing: %s", path, "TEMP")
 // }
 
-func InitMenuState() (*MenuState, error) {
+func InitMenuState(readOnly bool) (*MenuState, error) {
 	config, err := LoadConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	notes := NewNotesService(config)
+	if readOnly {
+		notes.SetWriteMode(WriteReadOnly)
+	}
 	nav := NewNavigator(notes)
 
+	menu := &MenuState{
+		Mode:   ModeBrowse,
+		config: config,
+		nav:    nav,
+		notes:  notes,
+	}
+	if config.RestoreSession {
+		if dirPath, selection, ok := notes.LoadSession(); ok && nav.NavigateTo(dirPath) == nil {
+			slog.Debug("Restored session", "dir", dirPath, "selection", selection)
+			menu.Selection = selection
+			return menu, nil
+		}
+	}
+
 	err = nav.NavigateTo("")
 	if err != nil {
 		return nil, err
 	}
-
-	menu := &MenuState{ModeBrowse, "", config, nav, notes}
 	return menu, nil
 }
 
+// saveSession remembers where the menu was left for the next launch, failing to only costs that convenience
+func (m *MenuState) saveSession() {
+	if !m.config.RestoreSession {
+		return
+	}
+	if err := m.notes.SaveSession(m.nav.CurrentDirectory().Path, m.Selection); err != nil {
+		slog.Debug("Failed to save session", "error", err)
+	}
+}
+
 func (m *MenuState) getNavigationMenuItems() []string {
 	entries := m.nav.ListEntries()
 	if m.nav.CurrentDirectory().Path != "" {
@@ -81,6 +147,24 @@ func (m *MenuState) getPrompt() string {
 		return "Pick a template: "
 	case ModeSettings:
 		return "Indexing: "
+	case ModePeriodic:
+		return "Periodic: "
+	case ModeCapture:
+		return "Capture: "
+	case ModeSearch:
+		return "Search: "
+	case ModeSearchResults:
+		return fmt.Sprintf("%d matches: ", len(m.hits))
+	case ModeQuickSwitch:
+		return "Go to: "
+	case ModeRecent:
+		return "Recent: "
+	case ModeTags:
+		return "Tags: "
+	case ModeTagged:
+		return fmt.Sprintf("#%s: ", m.tag)
+	case ModeLinks:
+		return fmt.Sprintf("Links of %s: ", Breadcrumb(m.linkNote))
 	default:
 		return "Browse: "
 	}
@@ -101,6 +185,20 @@ func (m *MenuState) handleChoice(choice string) error {
 		err = m.handleNewEntry(choice, false)
 	case ModeNewDirectory:
 		err = m.handleNewEntry(choice, true)
+	case ModePeriodic:
+		err = m.handlePeriodicChoice(choice)
+	case ModeCapture:
+		err = m.handleCaptureChoice(choice)
+	case ModeSearch:
+		err = m.handleSearchChoice(choice)
+	case ModeSearchResults:
+		err = m.handleSearchResultChoice(choice)
+	case ModeQuickSwitch, ModeRecent, ModeTagged:
+		err = m.handleNoteListChoice(choice)
+	case ModeTags:
+		err = m.handleTagChoice(choice)
+	case ModeLinks:
+		err = m.handleLinkChoice(choice)
 	}
 
 	return err
@@ -116,21 +214,43 @@ func (m *MenuState) getMenuItems() ([]string, error) {
 		return m.getBrowseMenuItems()
 	case ModePickTemplate:
 		return m.getNavigationMenuItems(), nil
+	case ModePeriodic:
+		return getPeriodicMenuItems()
+	case ModeSearchResults:
+		return m.getSearchResultMenuItems(), nil
+	case ModeQuickSwitch:
+		return m.getQuickSwitchMenuItems()
+	case ModeRecent:
+		return m.getRecentMenuItems()
+	case ModeTags:
+		return m.getTagMenuItems()
+	case ModeTagged:
+		return m.getTaggedMenuItems()
+	case ModeLinks:
+		return m.getLinkMenuItems()
 	default:
 		return nil, nil
 	}
 }
 
-func Browse() error {
-	menu, err := InitMenuState()
+// Browse runs the menu until an editor is launched or the user dismisses it
+func Browse(readOnly bool) (Result, error) {
+	menu, err := InitMenuState(readOnly)
 	if err != nil {
-		return err
+		return Result{}, err
 	}
 
 	for {
+		// Nothing reports the menu's changes, dropping them keeps a long session from piling them up
+		menu.notes.TakeChanges()
+
 		choice, err := menu.launchMenu()
+		if errors.Is(err, ErrCancelled) {
+			menu.saveSession()
+			return Result{Outcome: OutcomeCancelled}, nil
+		}
 		if err != nil {
-			return err
+			return Result{}, err
 		}
 
 		// Skip handling if choice is empty (file movement operations return empty string)
@@ -140,7 +260,11 @@ func Browse() error {
 
 		err = menu.handleChoice(choice)
 		if err != nil {
-			return err
+			return Result{}, err
+		}
+		if menu.result != nil {
+			menu.saveSession()
+			return *menu.result, nil
 		}
 
 		menu.nav.Reload()
@@ -3,6 +3,9 @@ package internal
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
 // General
@@ -10,12 +13,15 @@ import (
 func (m *MenuState) handleFileSelection(choice string, onFileSelect func(string) error) error {
 	switch choice {
 	case MenuBack:
+		// Highlight the directory we came out of
+		m.Selection = filepath.Base(m.nav.CurrentDirectory().Path)
 		return m.nav.NavigateToParent()
 	default:
 		entry := m.nav.CurrentDirectory().GetEntryByFilename(choice)
 		if entry == nil {
-			return fmt.Errorf("entry not found: %q", choice)
+			return fmt.Errorf("entry %w: %q", ErrNotFound, choice)
 		}
+		m.Selection = choice
 
 		fullPath := filepath.Join(m.nav.CurrentDirectory().Path, choice)
 		if entry.IsDir {
@@ -26,12 +32,39 @@ func (m *MenuState) handleFileSelection(choice string, onFileSelect func(string)
 	}
 }
 
+// launchNote opens the note and ends the menu loop with the launch as its result
+func (m *MenuState) launchNote(filePath string) error {
+	result, err := m.notes.LaunchNoteEditor(filePath)
+	if err != nil {
+		return err
+	}
+	m.result = &result
+	return nil
+}
+
 // Browse Mode
 
 func (m *MenuState) getBrowseMenuItems() ([]string, error) {
-	items := []string{MenuNew, MenuSettings}
+	var items []string
+	if m.nav.CurrentDirectory().Path == "" {
+		favorites, err := m.getFavoriteMenuItems()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, favorites...)
+	}
+	if !m.notes.IsReadOnly() {
+		items = append(items, MenuNew, MenuCapture, MenuPeriodic)
+	}
+	items = append(items, MenuSettings, MenuRecent, MenuQuickSwitch, MenuSearch, MenuTags)
 
 	items = append(items, m.getNavigationMenuItems()...)
+	if m.nav.CanGoBack() {
+		items = append(items, MenuHistoryBack)
+	}
+	if m.nav.CanGoForward() {
+		items = append(items, MenuHistoryForward)
+	}
 	items = append(items, MenuOpenCurrentFolder)
 	return items, nil
 }
@@ -44,11 +77,101 @@ func (m *MenuState) handleBrowseChoice(choice string) error {
 	case MenuSettings:
 		m.Mode = ModeSettings
 		return nil
+	case MenuPeriodic:
+		m.Mode = ModePeriodic
+		return nil
+	case MenuCapture:
+		m.Mode = ModeCapture
+		return nil
+	case MenuSearch:
+		m.Mode = ModeSearch
+		return nil
+	case MenuQuickSwitch:
+		m.Mode = ModeQuickSwitch
+		return nil
+	case MenuRecent:
+		m.Mode = ModeRecent
+		return nil
+	case MenuTags:
+		m.Mode = ModeTags
+		return nil
+	case MenuHistoryBack:
+		m.Selection = ""
+		return m.nav.Back()
+	case MenuHistoryForward:
+		m.Selection = ""
+		return m.nav.Forward()
 	case MenuOpenCurrentFolder:
-		return m.notes.LaunchDirectoryEditor(m.nav.CurrentDirectory().Path)
+		result, err := m.notes.LaunchDirectoryEditor(m.nav.CurrentDirectory().Path)
+		if err != nil {
+			return err
+		}
+		m.result = &result
+		return nil
+	}
+
+	if pin := m.findFavorite(choice); pin != nil {
+		if pin.IsDir {
+			return m.nav.NavigateTo(pin.Path)
+		}
+		return m.launchNote(pin.Path)
 	}
 
-	return m.handleFileSelection(choice, m.notes.LaunchNoteEditor)
+	return m.handleFileSelection(choice, m.launchNote)
+}
+
+// Favorites
+
+func formatFavorite(pin Pin) string {
+	name := Breadcrumb(pin.Path)
+	if pin.IsDir {
+		name += "/"
+	}
+	return fmt.Sprintf("%s%d  %s", MenuFavoritePrefix, pin.Number, name)
+}
+
+// getFavoriteMenuItems lists the pins that still exist, remembering them for findFavorite
+func (m *MenuState) getFavoriteMenuItems() ([]string, error) {
+	pins, err := m.notes.Pins()
+	if err != nil {
+		return nil, err
+	}
+
+	m.pins = nil
+	var items []string
+	for _, pin := range pins {
+		if pin.Path == "" {
+			continue
+		}
+		m.pins = append(m.pins, pin)
+		items = append(items, formatFavorite(pin))
+	}
+	return items, nil
+}
+
+func (m *MenuState) findFavorite(choice string) *Pin {
+	if m.nav.CurrentDirectory().Path != "" {
+		return nil
+	}
+	for i := range m.pins {
+		if formatFavorite(m.pins[i]) == choice {
+			return &m.pins[i]
+		}
+	}
+	return nil
+}
+
+// togglePin pins or unpins the highlighted entry or favorite
+func (m *MenuState) togglePin(selection string) error {
+	if pin := m.findFavorite(selection); pin != nil {
+		return m.notes.UnpinEntry(pin.Path)
+	}
+
+	entry := m.nav.CurrentDirectory().GetEntryByFilename(selection)
+	if entry == nil {
+		return nil
+	}
+	return m.notes.TogglePin(filepath.Join(m.nav.CurrentDirectory().Path, entry.String()))
 }
 
 // New Mode
@@ -108,7 +231,7 @@ func (m *MenuState) handleNewEntry(choice string, isDir bool) error {
 		return m.nav.NavigateTo(filePath)
 	}
 
-	return m.notes.LaunchNoteEditor(filePath)
+	return m.launchNote(filePath)
 }
 
 // Settings Mode
@@ -121,22 +244,36 @@ func formatSelectedOption(text string, selected bool) string {
 }
 
 func (m *MenuState) getSettingsMenuItems() ([]string, error) {
-	menuItems := []string{
-		formatSelectedOption(MenuIndexSetting, m.nav.CurrentDirectory().IsIndexed),
-		MenuBack,
+	currentDir := m.nav.CurrentDirectory()
+	var menuItems []string
+	// Pins live in the state directory, only the index toggle changes the garden
+	if !m.notes.IsReadOnly() {
+		menuItems = append(menuItems, formatSelectedOption(MenuIndexSetting, currentDir.IsIndexed))
+	}
+	if currentDir.Path != "" {
+		menuItems = append(menuItems, formatSelectedOption(MenuPinSetting, m.notes.IsPinned(currentDir.Path)))
 	}
 
-	return menuItems, nil
+	return append(menuItems, MenuBack), nil
 }
 
 func (m *MenuState) handleSettingsChoice(choice string) error {
 	currentDir := m.nav.CurrentDirectory()
 
 	switch choice {
-	case MenuIndexSetting:
-		currentDir.ApplyNumericIndexing()
-	case formatSelectedOption(MenuIndexSetting, true):
-		currentDir.RemoveIndexing()
+	case MenuIndexSetting, formatSelectedOption(MenuIndexSetting, true):
+		if m.notes.IsReadOnly() {
+			return fmt.Errorf("%w: can't change the indexing of %s", ErrReadOnly, currentDir.Path)
+		}
+		if choice == MenuIndexSetting {
+			currentDir.ApplyNumericIndexing()
+		} else {
+			currentDir.RemoveIndexing()
+		}
+	case MenuPinSetting, formatSelectedOption(MenuPinSetting, true):
+		if err := m.notes.TogglePin(currentDir.Path); err != nil {
+			return err
+		}
 	}
 
 	err := m.nav.NavigateTo(currentDir.Path)
@@ -167,3 +304,283 @@ func (m *MenuState) handleTemplateChoice(choice string) error {
 		return nil
 	})
 }
+
+// Periodic Mode
+
+type periodicMenuItem struct {
+	label  string
+	period Period
+	offset int
+}
+
+func periodicMenuItems() []periodicMenuItem {
+	items := []periodicMenuItem{
+		{"Today", PeriodDay, 0},
+		{"Yesterday", PeriodDay, -1},
+		{"Tomorrow", PeriodDay, 1},
+	}
+
+	for _, period := range Periods[1:] {
+		name := strings.ToUpper(period.String()[:1]) + period.String()[1:]
+		items = append(items,
+			periodicMenuItem{"This " + name, period, 0},
+			periodicMenuItem{"Last " + name, period, -1},
+			periodicMenuItem{"Next " + name, period, 1},
+		)
+	}
+	return items
+}
+
+func getPeriodicMenuItems() ([]string, error) {
+	var items []string
+	for _, item := range periodicMenuItems() {
+		items = append(items, item.label)
+	}
+	return append(items, MenuBack), nil
+}
+
+func (m *MenuState) handlePeriodicChoice(choice string) error {
+	if choice == MenuBack {
+		m.Mode = ModeBrowse
+		return nil
+	}
+
+	for _, item := range periodicMenuItems() {
+		if item.label != choice {
+			continue
+		}
+
+		filePath, err := m.notes.OpenPeriod(item.period, item.period.Add(time.Now(), item.offset))
+		if err != nil {
+			return err
+		}
+
+		m.Mode = ModeBrowse
+		return m.launchNote(filePath)
+	}
+
+	return fmt.Errorf("unknown periodic note: %q", choice)
+}
+
+// Capture Mode
+
+func (m *MenuState) handleCaptureChoice(choice string) error {
+	_, err := m.notes.Capture(choice, CaptureOptions{})
+	if err != nil {
+		return err
+	}
+
+	m.Mode = ModeBrowse
+	return nil
+}
+
+// Search Mode
+
+func (m *MenuState) handleSearchChoice(choice string) error {
+	hits, err := m.notes.Search(ParseSearchQuery(choice))
+	if err != nil {
+		return err
+	}
+
+	m.hits = hits
+	m.Mode = ModeSearchResults
+	return nil
+}
+
+func (m *MenuState) getSearchResultMenuItems() []string {
+	var items []string
+	for _, hit := range m.hits {
+		items = append(items, hit.String())
+	}
+	return append(items, MenuBack)
+}
+
+func (m *MenuState) handleSearchResultChoice(choice string) error {
+	if choice == MenuBack {
+		m.Mode = ModeSearch
+		return nil
+	}
+
+	for _, hit := range m.hits {
+		if hit.String() != choice {
+			continue
+		}
+
+		result, err := m.notes.LaunchNoteEditorAt(hit.Path, hit.Line)
+		if err != nil {
+			return err
+		}
+		m.Mode = ModeBrowse
+		m.result = &result
+		return nil
+	}
+
+	return fmt.Errorf("unknown search result: %q", choice)
+}
+
+// Quick Switch, Recent and Tagged Modes
+
+// noteListMenuItems shows notePaths as breadcrumbs, remembering them for handleNoteListChoice
+func (m *MenuState) noteListMenuItems(notePaths []string) []string {
+	m.notePaths = notePaths
+
+	var items []string
+	for _, notePath := range notePaths {
+		items = append(items, Breadcrumb(notePath))
+	}
+	return append(items, MenuBack)
+}
+
+func (m *MenuState) getQuickSwitchMenuItems() ([]string, error) {
+	notePaths, err := m.notes.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+	rankByFrecency(notePaths, m.notes.frecencies())
+	return m.noteListMenuItems(notePaths), nil
+}
+
+func (m *MenuState) getRecentMenuItems() ([]string, error) {
+	recent, err := m.notes.RecentNotes(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var notePaths []string
+	for _, note := range recent {
+		notePaths = append(notePaths, note.Path)
+	}
+	return m.noteListMenuItems(notePaths), nil
+}
+
+func (m *MenuState) handleNoteListChoice(choice string) error {
+	if choice == MenuBack && m.Mode == ModeTagged {
+		m.Mode = ModeTags
+		return nil
+	}
+	if choice == MenuBack {
+		m.Mode = ModeBrowse
+		return nil
+	}
+
+	for _, notePath := range m.notePaths {
+		if Breadcrumb(notePath) == choice {
+			m.Mode = ModeBrowse
+			return m.launchNote(notePath)
+		}
+	}
+
+	return fmt.Errorf("note %w: %q", ErrNotFound, choice)
+}
+
+func (m *MenuState) getTaggedMenuItems() ([]string, error) {
+	notePaths, err := m.notes.TaggedNotes(m.tag)
+	if err != nil {
+		return nil, err
+	}
+	return m.noteListMenuItems(notePaths), nil
+}
+
+// Tags Mode
+
+func formatTag(tag TagCount) string {
+	return fmt.Sprintf("#%s  (%d)", tag.Tag, tag.Count)
+}
+
+// getTagMenuItems lists every tag with its note count, remembering them for handleTagChoice
+func (m *MenuState) getTagMenuItems() ([]string, error) {
+	tags, err := m.notes.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	m.tags = tags
+	var items []string
+	for _, tag := range tags {
+		items = append(items, formatTag(tag))
+	}
+	return append(items, MenuBack), nil
+}
+
+func (m *MenuState) handleTagChoice(choice string) error {
+	if choice == MenuBack {
+		m.Mode = ModeBrowse
+		return nil
+	}
+
+	for _, tag := range m.tags {
+		if formatTag(tag) == choice {
+			m.tag = tag.Tag
+			m.Mode = ModeTagged
+			return nil
+		}
+	}
+	return fmt.Errorf("tag %w: %q", ErrNotFound, choice)
+}
+
+// Links Mode
+
+// linkMenuItem is a backlink or outgoing link of the note in links mode, path is "" for unresolved links
+type linkMenuItem struct {
+	label string
+	path  string
+	line  int
+}
+
+// getLinkMenuItems lists the backlinks to the note followed by its outgoing links, remembering them for handleLinkChoice
+func (m *MenuState) getLinkMenuItems() ([]string, error) {
+	backlinks, outgoing, err := m.notes.NoteLinks(m.linkNote)
+	if err != nil {
+		return nil, err
+	}
+
+	m.links = nil
+	for _, link := range backlinks {
+		label := fmt.Sprintf("%s%s:%d", MenuBacklinkPrefix, Breadcrumb(link.Source), link.Line)
+		m.links = append(m.links, linkMenuItem{label, link.Source, link.Line})
+	}
+	for _, link := range outgoing {
+		label := MenuOutgoingPrefix + link.Raw + " (missing)"
+		if link.Path != "" {
+			label = MenuOutgoingPrefix + Breadcrumb(link.Path)
+		}
+		// A note linked several times is only listed once
+		if slices.ContainsFunc(m.links, func(item linkMenuItem) bool { return item.label == label }) {
+			continue
+		}
+		m.links = append(m.links, linkMenuItem{label, link.Path, 0})
+	}
+
+	var items []string
+	for _, link := range m.links {
+		items = append(items, link.label)
+	}
+	return append(items, MenuBack), nil
+}
+
+func (m *MenuState) handleLinkChoice(choice string) error {
+	if choice == MenuBack {
+		m.Mode = ModeBrowse
+		return nil
+	}
+
+	for _, link := range m.links {
+		if link.label != choice {
+			continue
+		}
+		// Unresolved links have nothing to open
+		if link.path == "" {
+			return nil
+		}
+
+		result, err := m.notes.LaunchNoteEditorAt(link.path, link.line)
+		if err != nil {
+			return err
+		}
+		m.Mode = ModeBrowse
+		m.result = &result
+		return nil
+	}
+
+	return fmt.Errorf("unknown link: %q", choice)
+}
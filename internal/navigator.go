@@ -8,8 +8,12 @@ import (
 type Navigator struct {
 	currentDir    *Directory
 	savedDir      *Directory
+	savedHistory  int
 	savedTemplate string
-	notes         *EntryService
+	// Directories visited before and after the current one, most recent last
+	back    []string
+	forward []string
+	notes   *EntryService
 }
 
 func NewNavigator(notes *EntryService) *Navigator {
@@ -18,7 +22,21 @@ func NewNavigator(notes *EntryService) *Navigator {
 	}
 }
 
+// NavigateTo loads the directory, remembering the one it leaves so Back can return to it
 func (n *Navigator) NavigateTo(dirPath string) error {
+	previous := n.currentDir
+	if err := n.load(dirPath); err != nil {
+		return err
+	}
+
+	if previous != nil && previous.Path != dirPath {
+		n.back = append(n.back, previous.Path)
+		n.forward = nil
+	}
+	return nil
+}
+
+func (n *Navigator) load(dirPath string) error {
 	dir, err := n.notes.LoadDirectory(dirPath)
 	if err != nil {
 		return err
@@ -40,12 +58,47 @@ func (n *Navigator) NavigateToParent() error {
 	return n.NavigateTo(parentPath)
 }
 
+func (n *Navigator) CanGoBack() bool {
+	return len(n.back) > 0
+}
+
+func (n *Navigator) CanGoForward() bool {
+	return len(n.forward) > 0
+}
+
+// Back returns to the directory visited before the current one, skipping any that no longer exist
+func (n *Navigator) Back() error {
+	return n.step(&n.back, &n.forward)
+}
+
+// Forward undoes Back
+func (n *Navigator) Forward() error {
+	return n.step(&n.forward, &n.back)
+}
+
+func (n *Navigator) step(from *[]string, to *[]string) error {
+	for len(*from) > 0 {
+		target := (*from)[len(*from)-1]
+		*from = (*from)[:len(*from)-1]
+
+		current := n.currentDir.Path
+		if err := n.load(target); err != nil {
+			continue
+		}
+		*to = append(*to, current)
+		return nil
+	}
+	return fmt.Errorf("no directory to go to in the history")
+}
+
 func (n *Navigator) Reload() error {
 	return n.currentDir.LoadEntries()
 }
 
+// Save marks where a detour like picking a template starts, Restore returns there and forgets the detour's history
 func (n *Navigator) Save() {
 	n.savedDir = n.currentDir
+	n.savedHistory = len(n.back)
 }
 
 func (n *Navigator) Restore() (*Directory, error) {
@@ -55,6 +108,9 @@ func (n *Navigator) Restore() (*Directory, error) {
 
 	restored := n.savedDir
 	n.savedDir = nil
+	n.currentDir = restored
+	n.back = n.back[:min(n.savedHistory, len(n.back))]
+	n.forward = nil
 	return restored, nil
 }
 
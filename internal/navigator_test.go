@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNavigatorHistory(t *testing.T) {
+	type step struct {
+		action      string
+		arg         string
+		want        string
+		wantBack    bool
+		wantForward bool
+		wantErr     bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "back and forward",
+			steps: []step{
+				{action: "go", arg: "A", want: "A", wantBack: true},
+				{action: "go", arg: "A/B", want: "A/B", wantBack: true},
+				{action: "back", want: "A", wantBack: true, wantForward: true},
+				{action: "back", want: "", wantForward: true},
+				{action: "forward", want: "A", wantBack: true, wantForward: true},
+				{action: "forward", want: "A/B", wantBack: true},
+			},
+		},
+		{
+			name: "new visit drops forward history",
+			steps: []step{
+				{action: "go", arg: "A", want: "A", wantBack: true},
+				{action: "back", want: "", wantForward: true},
+				{action: "go", arg: "C", want: "C", wantBack: true},
+				{action: "back", want: "", wantForward: true},
+				{action: "forward", want: "C", wantBack: true},
+				{action: "forward", want: "C", wantBack: true, wantErr: true},
+			},
+		},
+		{
+			name: "staying put isn't a visit",
+			steps: []step{
+				{action: "go", arg: "", want: ""},
+				{action: "go", arg: "A", want: "A", wantBack: true},
+				{action: "go", arg: "A", want: "A", wantBack: true},
+				{action: "back", want: "", wantForward: true},
+			},
+		},
+		{
+			name: "parent is a visit",
+			steps: []step{
+				{action: "go", arg: "A/B", want: "A/B", wantBack: true},
+				{action: "parent", want: "A", wantBack: true},
+				{action: "back", want: "A/B", wantBack: true, wantForward: true},
+			},
+		},
+		{
+			name: "removed directories are skipped",
+			steps: []step{
+				{action: "go", arg: "A", want: "A", wantBack: true},
+				{action: "go", arg: "C", want: "C", wantBack: true},
+				{action: "remove", arg: "A", want: "C", wantBack: true},
+				{action: "back", want: "", wantForward: true},
+			},
+		},
+		{
+			name: "restore forgets the detour",
+			steps: []step{
+				{action: "go", arg: "A", want: "A", wantBack: true},
+				{action: "save", want: "A", wantBack: true},
+				{action: "go", arg: "C", want: "C", wantBack: true},
+				{action: "restore", want: "A", wantBack: true},
+				{action: "back", want: "", wantForward: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, "A/B/", "C/")
+			nav := NewNavigator(s)
+			if err := nav.NavigateTo(""); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, step := range tt.steps {
+				var err error
+				switch step.action {
+				case "go":
+					err = nav.NavigateTo(step.arg)
+				case "parent":
+					err = nav.NavigateToParent()
+				case "back":
+					err = nav.Back()
+				case "forward":
+					err = nav.Forward()
+				case "remove":
+					err = os.RemoveAll(filepath.Join(s.config.RootDir, step.arg))
+				case "save":
+					nav.Save()
+				case "restore":
+					_, err = nav.Restore()
+				}
+				if (err != nil) != step.wantErr {
+					t.Fatalf("step %d %s %q error = %v", i, step.action, step.arg, err)
+				}
+
+				if got := nav.CurrentDirectory().Path; got != step.want {
+					t.Errorf("step %d %s: current = %q, want %q", i, step.action, got, step.want)
+				}
+				if nav.CanGoBack() != step.wantBack || nav.CanGoForward() != step.wantForward {
+					t.Errorf("step %d %s: back %v forward %v, want %v %v", i, step.action,
+						nav.CanGoBack(), nav.CanGoForward(), step.wantBack, step.wantForward)
+				}
+			}
+		})
+	}
+}
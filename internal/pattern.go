@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// Date tokens, longest first so YYYY wins over YY
+var dateTokens = []struct {
+	token  string
+	format func(t time.Time) string
+}{
+	{"YYYY", func(t time.Time) string { return t.Format("2006") }},
+	{"GGGG", func(t time.Time) string { year, _ := t.ISOWeek(); return strconv.Itoa(year) }},
+	{"MMMM", func(t time.Time) string { return t.Format("January") }},
+	{"dddd", func(t time.Time) string { return t.Format("Monday") }},
+	{"MMM", func(t time.Time) string { return t.Format("Jan") }},
+	{"ddd", func(t time.Time) string { return t.Format("Mon") }},
+	{"YY", func(t time.Time) string { return t.Format("06") }},
+	{"MM", func(t time.Time) string { return t.Format("01") }},
+	{"DD", func(t time.Time) string { return t.Format("02") }},
+	{"WW", func(t time.Time) string { _, week := t.ISOWeek(); return fmt.Sprintf("%02d", week) }},
+	{"Q", func(t time.Time) string { return strconv.Itoa((int(t.Month())-1)/3 + 1) }},
+}
+
+// ExpandDatePattern replaces every {{...}} placeholder made of date tokens with the formatted date.
+// Supported tokens: YYYY, YY, GGGG (ISO week year), MMMM, MMM, MM, DD, dddd, ddd, WW (ISO week) and Q.
+// Placeholders containing anything else are left untouched.
+func ExpandDatePattern(pattern string, t time.Time) string {
+	return RenderPlaceholders(pattern, nil, t)
+}
+
+// RenderPlaceholders substitutes named variables first, then date token placeholders
+func RenderPlaceholders(content string, vars map[string]string, t time.Time) string {
+	return placeholderPattern.ReplaceAllStringFunc(content, func(match string) string {
+		inner := placeholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[inner]; ok {
+			return value
+		}
+		if expanded, ok := expandDateTokens(inner, t); ok {
+			return expanded
+		}
+		return match
+	})
+}
+
+func expandDateTokens(inner string, t time.Time) (string, bool) {
+	var result strings.Builder
+	for len(inner) > 0 {
+		matched := false
+		for _, dt := range dateTokens {
+			if strings.HasPrefix(inner, dt.token) {
+				result.WriteString(dt.format(t))
+				inner = inner[len(dt.token):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		r := rune(inner[0])
+		if unicode.IsLetter(r) {
+			return "", false
+		}
+		result.WriteByte(inner[0])
+		inner = inner[1:]
+	}
+	return result.String(), true
+}
+
+// TemplateVars returns the variables available to every rendered template
+func TemplateVars(title string, t time.Time) map[string]string {
+	return map[string]string{
+		"title": title,
+		"date":  t.Format("2006-01-02"),
+		"time":  t.Format("15:04"),
+	}
+}
+
+// ParseDateArg parses today, yesterday, tomorrow, +N/-N day offsets or a YYYY-MM-DD date
+func ParseDateArg(value string, now time.Time) (time.Time, error) {
+	switch value {
+	case "", "today":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	}
+
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		offset, err := strconv.Atoi(value)
+		if err == nil {
+			return now.AddDate(0, 0, offset), nil
+		}
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q (expected today, yesterday, tomorrow, +N, -N or YYYY-MM-DD)", ErrValidation, value)
+	}
+	return date, nil
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderPlaceholders(t *testing.T) {
+	// A Monday in ISO week 1 of 2025, so the week year differs from the calendar year
+	day := time.Date(2024, 12, 30, 14, 5, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		content string
+		vars    map[string]string
+		want    string
+	}{
+		{
+			name:    "date tokens",
+			content: "{{YYYY}}/{{MM}}/{{YYYY-MM-DD}} {{YY}} {{Q}}",
+			want:    "2024/12/2024-12-30 24 4",
+		},
+		{
+			name:    "names",
+			content: "{{dddd}} {{ddd}}, {{MMMM}} {{MMM}} {{DD}}",
+			want:    "Monday Mon, December Dec 30",
+		},
+		{
+			name:    "iso week",
+			content: "{{GGGG}}-W{{WW}}",
+			want:    "2025-W01",
+		},
+		{
+			name:    "template variables",
+			content: "{{title}} on {{date}} at {{time}}",
+			vars:    TemplateVars("Standup", day),
+			want:    "Standup on 2024-12-30 at 14:05",
+		},
+		{
+			name:    "variables win over date tokens",
+			content: "{{MM}}",
+			vars:    map[string]string{"MM": "mine"},
+			want:    "mine",
+		},
+		{
+			name:    "unknown placeholders are left alone",
+			content: "{{title}} {{author}} {{YYYY}}x{{}}",
+			want:    "{{title}} {{author}} 2024x{{}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderPlaceholders(tt.content, tt.vars, day); got != tt.want {
+				t.Errorf("RenderPlaceholders() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
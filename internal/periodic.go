@@ -0,0 +1,248 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Period int
+
+const (
+	PeriodDay Period = iota
+	PeriodWeek
+	PeriodMonth
+	PeriodQuarter
+	PeriodYear
+)
+
+var Periods = []Period{PeriodDay, PeriodWeek, PeriodMonth, PeriodQuarter, PeriodYear}
+
+func (p Period) String() string {
+	switch p {
+	case PeriodDay:
+		return "day"
+	case PeriodWeek:
+		return "week"
+	case PeriodMonth:
+		return "month"
+	case PeriodQuarter:
+		return "quarter"
+	case PeriodYear:
+		return "year"
+	default:
+		return ""
+	}
+}
+
+func ParsePeriod(value string) (Period, error) {
+	switch value {
+	case "day", "daily":
+		return PeriodDay, nil
+	case "week", "weekly":
+		return PeriodWeek, nil
+	case "month", "monthly":
+		return PeriodMonth, nil
+	case "quarter", "quarterly":
+		return PeriodQuarter, nil
+	case "year", "yearly":
+		return PeriodYear, nil
+	default:
+		return PeriodDay, fmt.Errorf("%w: unknown period %q (expected day, week, month, quarter or year)", ErrValidation, value)
+	}
+}
+
+// Start returns midnight on the first day of the period containing t, weeks start on Monday
+func (p Period) Start(t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch p {
+	case PeriodWeek:
+		weekday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-weekday, 0, 0, 0, 0, t.Location())
+	case PeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case PeriodQuarter:
+		quarterMonth := time.Month((int(month)-1)/3*3 + 1)
+		return time.Date(year, quarterMonth, 1, 0, 0, 0, 0, t.Location())
+	case PeriodYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Add moves t by n periods, keeping it aligned to the period start
+func (p Period) Add(t time.Time, n int) time.Time {
+	start := p.Start(t)
+	switch p {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7*n)
+	case PeriodMonth:
+		return start.AddDate(0, n, 0)
+	case PeriodQuarter:
+		return start.AddDate(0, 3*n, 0)
+	case PeriodYear:
+		return start.AddDate(n, 0, 0)
+	default:
+		return start.AddDate(0, 0, n)
+	}
+}
+
+// EnsureDirectory walks dirPath from the root, creating any missing directories along the way.
+// Existing directories are matched regardless of their index, new ones respect their parent's indexing.
+func (s *EntryService) EnsureDirectory(dirPath string) (*Directory, error) {
+	dir, err := s.LoadDirectory("")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, segment := range strings.Split(filepath.Clean(dirPath), string(filepath.Separator)) {
+		if segment == "" || segment == "." {
+			continue
+		}
+
+		var childPath string
+		if entry := dir.FindEntry(segment); entry != nil && entry.IsDir {
+			childPath = filepath.Join(dir.Path, entry.String())
+		} else {
+			_, cleanName, err := parseEntryName(segment)
+			if err != nil {
+				return nil, err
+			}
+
+			slog.Debug("Creating missing directory", "parent", dir.Path, "name", cleanName)
+			entry := &Entry{
+				Name:       cleanName,
+				EntryIndex: dir.NewDirIndex(),
+				IsDir:      true,
+			}
+			childPath, err = s.createEntry(dir, entry, nil, CollisionOpen)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		dir, err = s.LoadDirectory(childPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dir, nil
+}
+
+// EnsureNote expands the path pattern for t and returns that note, creating it from its template if it doesn't exist yet
+func (s *EntryService) EnsureNote(periodic PeriodicConfig, t time.Time) (string, error) {
+	if periodic.Path == "" {
+		return "", fmt.Errorf("no path pattern configured for note")
+	}
+
+	notePath := ExpandDatePattern(periodic.Path, t)
+	dirPath, filename := filepath.Split(notePath)
+	slog.Debug("Ensuring note", "pattern", periodic.Path, "path", notePath)
+
+	dir, err := s.EnsureDirectory(dirPath)
+	if err != nil {
+		return "", err
+	}
+
+	if existing := dir.FindEntry(filename); existing != nil && !existing.IsDir {
+		return filepath.Join(dir.Path, existing.String()), nil
+	}
+
+	ext := filepath.Ext(filename)
+	if ext == "" {
+		ext = ".md"
+	}
+	_, name, err := parseEntryName(strings.TrimSuffix(filename, filepath.Ext(filename)))
+	if err != nil {
+		return "", err
+	}
+
+	content := titleHeading
+	if periodic.Template != "" {
+		templatePath, err := s.ResolveTemplate(periodic.Template)
+		if err != nil {
+			return "", err
+		}
+		content, err = s.templateContent(templatePath, t)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	entry := &Entry{
+		Name:       name,
+		EntryIndex: dir.NewFileIndex(),
+		Ext:        ext,
+	}
+	return s.createEntry(dir, entry, content, CollisionOpen)
+}
+
+func (s *EntryService) OpenPeriod(period Period, t time.Time) (string, error) {
+	return s.EnsureNote(s.config.Periodic(period), period.Start(t))
+}
+
+// FindPath resolves a path relative to the root without creating anything, matching each segment regardless of index
+func (s *EntryService) FindPath(relPath string) (string, bool, error) {
+	dir, err := s.LoadDirectory("")
+	if err != nil {
+		return "", false, err
+	}
+
+	segments := strings.Split(filepath.Clean(relPath), string(filepath.Separator))
+	for i, segment := range segments {
+		if segment == "" || segment == "." {
+			continue
+		}
+
+		entry := dir.FindEntry(segment)
+		if entry == nil {
+			return "", false, nil
+		}
+
+		entryPath := filepath.Join(dir.Path, entry.String())
+		if i == len(segments)-1 {
+			return entryPath, true, nil
+		}
+		if !entry.IsDir {
+			return "", false, nil
+		}
+
+		dir, err = s.LoadDirectory(entryPath)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	return dir.Path, true, nil
+}
+
+type MissingPeriod struct {
+	Start time.Time
+	Path  string
+}
+
+// MissingPeriods lists every period between from and to whose note doesn't exist yet
+func (s *EntryService) MissingPeriods(period Period, from time.Time, to time.Time) ([]MissingPeriod, error) {
+	periodic := s.config.Periodic(period)
+	if periodic.Path == "" {
+		return nil, fmt.Errorf("no path pattern configured for %s notes", period)
+	}
+
+	var missing []MissingPeriod
+	for t := period.Start(from); !t.After(to); t = period.Add(t, 1) {
+		notePath := ExpandDatePattern(periodic.Path, t)
+		_, found, err := s.FindPath(notePath)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			missing = append(missing, MissingPeriod{Start: t, Path: notePath})
+		}
+	}
+
+	return missing, nil
+}
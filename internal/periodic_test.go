@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEnsureNoteIsIdempotent(t *testing.T) {
+	day := time.Date(2026, 10, 19, 9, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		garden  []string
+		pattern string
+		want    string
+	}{
+		{
+			name:    "root",
+			garden:  []string{"Inbox/"},
+			pattern: "{{YYYY-MM-DD}}.md",
+			want:    "2026-10-19.md",
+		},
+		{
+			name:    "indexed root",
+			garden:  []string{".index", "01. Inbox/.index"},
+			pattern: "{{YYYY-MM-DD}}.md",
+			want:    "02. 2026-10-19.md",
+		},
+		{
+			name:    "created directories",
+			garden:  []string{".index"},
+			pattern: "Journal/{{YYYY}}/{{MM}}.md",
+			want:    "01. Journal/2026/10.md",
+		},
+		{
+			name:    "existing note ignoring its index",
+			garden:  []string{".index", "01. Journal/.index", "01. Journal/03. 2026-10-19.md"},
+			pattern: "Journal/{{YYYY-MM-DD}}.md",
+			want:    "01. Journal/03. 2026-10-19.md",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, tt.garden...)
+			s.config.InboxDir = "Inbox"
+			periodic := PeriodicConfig{Path: tt.pattern}
+
+			first, err := s.EnsureNote(periodic, day)
+			if err != nil {
+				t.Fatalf("EnsureNote() error = %v", err)
+			}
+			files := gardenFiles(t, s)
+			second, err := s.EnsureNote(periodic, day)
+			if err != nil {
+				t.Fatalf("EnsureNote() again error = %v", err)
+			}
+
+			if first != tt.want || second != tt.want {
+				t.Errorf("EnsureNote() = %q then %q, want %q both times", first, second, tt.want)
+			}
+			if got := gardenFiles(t, s); !slices.Equal(got, files) {
+				t.Errorf("second EnsureNote() changed the garden from %v to %v", files, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// Pin is a bookmarked note or directory. Number is what jump takes and stays the same until the pin is removed,
+// Path is relative to the root and "" when the entry no longer exists.
+type Pin struct {
+	Number int
+	Key    string
+	Path   string
+	IsDir  bool
+}
+
+// storedPin is a pin as the state file keeps it, by its index-free path
+type storedPin struct {
+	Number int    `json:"number"`
+	Key    string `json:"key"`
+}
+
+// UnmarshalJSON also reads the bare paths older state files stored, loadState numbers those by position
+func (p *storedPin) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &p.Key); err == nil {
+		return nil
+	}
+	type plain storedPin
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+func pinIndex(pins []storedPin, key string) int {
+	return slices.IndexFunc(pins, func(pin storedPin) bool { return pin.Key == key })
+}
+
+// Pins returns every pin by number
+func (s *EntryService) Pins() ([]Pin, error) {
+	state, err := s.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var pins []Pin
+	for _, stored := range state.Pins {
+		pin := Pin{Number: stored.Number, Key: stored.Key}
+		if entryPath, found, err := s.FindPath(stored.Key); err == nil && found && entryPath != "" {
+			if _, entry, err := s.loadEntry(entryPath); err == nil {
+				pin.Path = entryPath
+				pin.IsDir = entry.IsDir
+			}
+		}
+		pins = append(pins, pin)
+	}
+	slices.SortFunc(pins, func(a, b Pin) int { return cmp.Compare(a.Number, b.Number) })
+	return pins, nil
+}
+
+// GetPin returns the pin jump <number> goes to
+func (s *EntryService) GetPin(number int) (Pin, error) {
+	pins, err := s.Pins()
+	if err != nil {
+		return Pin{}, err
+	}
+	i := slices.IndexFunc(pins, func(pin Pin) bool { return pin.Number == number })
+	if i < 0 {
+		return Pin{}, fmt.Errorf("bookmark %w: %d (there are %d)", ErrNotFound, number, len(pins))
+	}
+
+	pin := pins[i]
+	if pin.Path == "" {
+		return Pin{}, fmt.Errorf("bookmark %d points to %s, which %w", number, pin.Key, ErrNotFound)
+	}
+	return pin, nil
+}
+
+// PinEntry bookmarks an entry and returns its number, the lowest one free. Pinning it again keeps the number it has.
+func (s *EntryService) PinEntry(entryPath string) (int, error) {
+	if _, _, err := s.loadEntry(entryPath); err != nil {
+		return 0, err
+	}
+
+	state, err := s.loadState()
+	if err != nil {
+		return 0, err
+	}
+
+	key := StripIndexes(entryPath)
+	if i := pinIndex(state.Pins, key); i >= 0 {
+		return state.Pins[i].Number, nil
+	}
+
+	number := 1
+	for slices.ContainsFunc(state.Pins, func(pin storedPin) bool { return pin.Number == number }) {
+		number++
+	}
+	state.Pins = append(state.Pins, storedPin{Number: number, Key: key})
+	if err := state.save(); err != nil {
+		return 0, err
+	}
+
+	slog.Debug("Pinned entry", "path", key, "number", number)
+	return number, nil
+}
+
+// UnpinEntry removes the bookmark for an entry, which may be a pinned path that no longer exists.
+// The other pins keep their numbers, the gap is filled by the next pin.
+func (s *EntryService) UnpinEntry(entryPath string) error {
+	state, err := s.loadState()
+	if err != nil {
+		return err
+	}
+
+	key := StripIndexes(entryPath)
+	i := pinIndex(state.Pins, key)
+	if i < 0 {
+		return fmt.Errorf("pin %w: %s", ErrNotFound, entryPath)
+	}
+	state.Pins = slices.Delete(state.Pins, i, i+1)
+	if err := state.save(); err != nil {
+		return err
+	}
+
+	slog.Debug("Unpinned entry", "path", key)
+	return nil
+}
+
+// IsPinned reports whether an entry is bookmarked, an unreadable state file pins nothing
+func (s *EntryService) IsPinned(entryPath string) bool {
+	state, err := s.loadState()
+	if err != nil {
+		slog.Debug("Failed to load pins", "error", err)
+		return false
+	}
+	return pinIndex(state.Pins, StripIndexes(entryPath)) >= 0
+}
+
+// TogglePin pins an entry that isn't pinned and unpins one that is
+func (s *EntryService) TogglePin(entryPath string) error {
+	if s.IsPinned(entryPath) {
+		return s.UnpinEntry(entryPath)
+	}
+	_, err := s.PinEntry(entryPath)
+	return err
+}
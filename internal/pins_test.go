@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPinNumbers(t *testing.T) {
+	garden := []string{".index", "01. Inbox/a.md", "01. Inbox/b.md", "02. Projects/", "03. Archive/"}
+
+	tests := []struct {
+		name  string
+		steps func(t *testing.T, s *EntryService) int
+		want  int
+	}{
+		{
+			name:  "first pin",
+			steps: func(t *testing.T, s *EntryService) int { return pin(t, s, "01. Inbox/a.md") },
+			want:  1,
+		},
+		{
+			name: "next pin",
+			steps: func(t *testing.T, s *EntryService) int {
+				pin(t, s, "01. Inbox/a.md")
+				return pin(t, s, "02. Projects")
+			},
+			want: 2,
+		},
+		{
+			name: "pinning again keeps the number",
+			steps: func(t *testing.T, s *EntryService) int {
+				pin(t, s, "01. Inbox/a.md")
+				pin(t, s, "02. Projects")
+				return pin(t, s, "01. Inbox/a.md")
+			},
+			want: 1,
+		},
+		{
+			name: "later pins keep their number",
+			steps: func(t *testing.T, s *EntryService) int {
+				pin(t, s, "01. Inbox/a.md")
+				pin(t, s, "02. Projects")
+				unpin(t, s, "01. Inbox/a.md")
+				return number(t, s, "02. Projects")
+			},
+			want: 2,
+		},
+		{
+			name: "lowest free number is reused",
+			steps: func(t *testing.T, s *EntryService) int {
+				pin(t, s, "01. Inbox/a.md")
+				pin(t, s, "02. Projects")
+				pin(t, s, "03. Archive")
+				unpin(t, s, "01. Inbox/a.md")
+				return pin(t, s, "01. Inbox/b.md")
+			},
+			want: 1,
+		},
+		{
+			name: "pins follow a reindexed entry",
+			steps: func(t *testing.T, s *EntryService) int {
+				pin(t, s, "01. Inbox/a.md")
+				pin(t, s, "02. Projects")
+				if err := os.Rename(filepath.Join(s.config.RootDir, "02. Projects"), filepath.Join(s.config.RootDir, "04. Projects")); err != nil {
+					t.Fatal(err)
+				}
+				return number(t, s, "04. Projects")
+			},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, garden...)
+			if got := tt.steps(t, s); got != tt.want {
+				t.Errorf("number = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinsFromOlderStateFile(t *testing.T) {
+	s := newTestService(t, "Inbox/a.md", "Projects/")
+	state, err := s.loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(state.path, []byte(`{"pins": ["Projects", "Gone.md"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pins, err := s.Pins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Pin{{Number: 1, Key: "Projects", Path: "Projects", IsDir: true}, {Number: 2, Key: "Gone.md"}}
+	if len(pins) != len(want) || pins[0] != want[0] || pins[1] != want[1] {
+		t.Errorf("Pins() = %+v, want %+v", pins, want)
+	}
+
+	if _, err := s.GetPin(2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetPin() of a pin whose entry is gone error = %v, want %v", err, ErrNotFound)
+	}
+	if got := pin(t, s, "Inbox/a.md"); got != 3 {
+		t.Errorf("PinEntry() = %d, want 3", got)
+	}
+	if err := s.UnpinEntry("Gone.md"); err != nil {
+		t.Errorf("UnpinEntry() of a missing entry error = %v", err)
+	}
+}
+
+func pin(t *testing.T, s *EntryService, entryPath string) int {
+	t.Helper()
+	number, err := s.PinEntry(entryPath)
+	if err != nil {
+		t.Fatalf("PinEntry(%q) error = %v", entryPath, err)
+	}
+	return number
+}
+
+func unpin(t *testing.T, s *EntryService, entryPath string) {
+	t.Helper()
+	if err := s.UnpinEntry(entryPath); err != nil {
+		t.Fatalf("UnpinEntry(%q) error = %v", entryPath, err)
+	}
+}
+
+// number returns the number of the pin that resolves to entryPath
+func number(t *testing.T, s *EntryService, entryPath string) int {
+	t.Helper()
+	pins, err := s.Pins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pin := range pins {
+		if pin.Path == entryPath {
+			return pin.Number
+		}
+	}
+	t.Fatalf("no pin resolves to %s in %+v", entryPath, pins)
+	return 0
+}
@@ -0,0 +1,305 @@
+package internal
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// AmbiguousPathError is returned when a fuzzy path matches more than one entry equally well
+type AmbiguousPathError struct {
+	Query      string
+	Candidates []string
+}
+
+func (e *AmbiguousPathError) Error() string {
+	return fmt.Sprintf("ambiguous path %q matches %s", e.Query, strings.Join(e.Candidates, ", "))
+}
+
+// Picker chooses one of several candidate paths, used instead of failing on ambiguous matches
+type Picker func(query string, candidates []string) (string, error)
+
+type entryKind int
+
+const (
+	kindAny entryKind = iota
+	kindNote
+	kindDir
+)
+
+func (k entryKind) matches(isDir bool) bool {
+	switch k {
+	case kindNote:
+		return !isDir
+	case kindDir:
+		return isDir
+	default:
+		return true
+	}
+}
+
+func (s *EntryService) SetPicker(picker Picker) {
+	s.picker = picker
+}
+
+// ResolvePath turns a user supplied path into the matching path relative to the root
+func (s *EntryService) ResolvePath(query string) (string, error) {
+	return s.resolve(query, kindAny)
+}
+
+// ResolveNote is ResolvePath restricted to notes
+func (s *EntryService) ResolveNote(query string) (string, error) {
+	return s.resolve(query, kindNote)
+}
+
+// ResolveDir is ResolvePath restricted to directories, "" resolves to the root
+func (s *EntryService) ResolveDir(query string) (string, error) {
+	return s.resolve(query, kindDir)
+}
+
+// ResolveDirExact is ResolveDir without the fuzzy fallback, for commands that change the directory
+func (s *EntryService) ResolveDirExact(query string) (string, error) {
+	return s.resolveExact(query, kindDir)
+}
+
+// resolveExact only accepts the path as given, where indexes and the .md extension may be left out. Commands that change
+// the garden use it so a near miss fails, naming the closest entries, instead of acting on a different entry.
+func (s *EntryService) resolveExact(query string, kind entryKind) (string, error) {
+	if strings.Trim(query, "/. ") == "" && kind != kindNote {
+		return "", nil
+	}
+
+	candidates := []string{query}
+	if kind != kindDir && filepath.Ext(query) == "" {
+		candidates = append(candidates, query+".md")
+	}
+	for _, candidate := range candidates {
+		resolved, found, err := s.FindPath(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !found || resolved == "" {
+			continue
+		}
+		if _, entry, err := s.loadEntry(resolved); err == nil && kind.matches(entry.IsDir) {
+			return resolved, nil
+		}
+	}
+
+	matches, err := s.fuzzyMatches(query, kind)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("entry %w: %s", ErrNotFound, query)
+	}
+	var closest []string
+	for _, match := range matches[:min(len(matches), 3)] {
+		closest = append(closest, match.path)
+	}
+	return "", fmt.Errorf("entry %w: %s (did you mean %s?)", ErrNotFound, query, strings.Join(closest, ", "))
+}
+
+// resolve tries the path as given first, then falls back to fuzzy matching across the whole garden.
+// Fuzzy matching ignores index prefixes, case, the .md extension and separators, and lets the query skip levels.
+func (s *EntryService) resolve(query string, kind entryKind) (string, error) {
+	if strings.Trim(query, "/. ") == "" && kind != kindNote {
+		return "", nil
+	}
+
+	resolved, found, err := s.FindPath(query)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		if resolved == "" && kind != kindNote {
+			return resolved, nil
+		}
+		if _, entry, err := s.loadEntry(resolved); err == nil && kind.matches(entry.IsDir) {
+			return resolved, nil
+		}
+	}
+
+	matches, err := s.fuzzyMatches(query, kind)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("entry %w: %s", ErrNotFound, query)
+	}
+
+	var best []string
+	for _, match := range matches {
+		if match.score == matches[0].score {
+			best = append(best, match.path)
+		}
+	}
+	slog.Debug("Fuzzy resolved path", "query", query, "best", best, "matches", len(matches))
+
+	if len(best) == 1 {
+		return best[0], nil
+	}
+
+	// Equally good matches are told apart by how often and recently they were opened
+	scores := s.frecencies()
+	rankByFrecency(best, scores)
+	if scores[StripIndexes(best[0])] > scores[StripIndexes(best[1])] {
+		slog.Debug("Resolved tie by frecency", "query", query, "path", best[0])
+		return best[0], nil
+	}
+	if s.picker != nil {
+		return s.picker(query, best)
+	}
+	return "", &AmbiguousPathError{Query: query, Candidates: best}
+}
+
+type pathMatch struct {
+	path  string
+	score int
+}
+
+func (s *EntryService) fuzzyMatches(query string, kind entryKind) ([]pathMatch, error) {
+	var segments []string
+	for _, segment := range strings.Split(filepath.ToSlash(query), "/") {
+		if normalized := normalizeName(segment); normalized != "" {
+			segments = append(segments, normalized)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, nil
+	}
+
+	var matches []pathMatch
+	err := s.WalkGarden(func(entryPath string, entry *Entry) error {
+		if !kind.matches(entry.IsDir) {
+			return nil
+		}
+
+		var components []string
+		for _, component := range strings.Split(entryPath, string(filepath.Separator)) {
+			components = append(components, normalizeName(component))
+		}
+
+		if score := scorePath(segments, components); score > 0 {
+			matches = append(matches, pathMatch{path: entryPath, score: score})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(matches, func(a, b pathMatch) int { return b.score - a.score })
+	return matches, nil
+}
+
+// WalkGarden calls fn for every entry below the root, parents before their children.
+// Returning fs.SkipDir for a directory leaves out everything inside it.
+func (s *EntryService) WalkGarden(fn func(entryPath string, entry *Entry) error) error {
+	return s.walkDirectory("", fn)
+}
+
+func (s *EntryService) walkDirectory(dirPath string, fn func(entryPath string, entry *Entry) error) error {
+	dir, err := s.LoadDirectory(dirPath)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range dir.Entries {
+		entryPath := filepath.Join(dir.Path, entry.String())
+		err := fn(entryPath, entry)
+		if err == fs.SkipDir && entry.IsDir {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir {
+			if err := s.walkDirectory(entryPath, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// scorePath matches the last segment against the entry itself and the others, in order, against its ancestors.
+// Every ancestor the query skips costs a little so shallower matches win ties.
+func scorePath(segments []string, components []string) int {
+	last := len(components) - 1
+	score := scoreName(segments[len(segments)-1], components[last])
+	if score <= 0 {
+		return 0
+	}
+
+	ancestor := 0
+	for _, segment := range segments[:len(segments)-1] {
+		matched := false
+		for ; ancestor < last; ancestor++ {
+			if segmentScore := scoreName(segment, components[ancestor]); segmentScore > 0 {
+				score += segmentScore
+				ancestor++
+				matched = true
+				break
+			}
+			score -= 5
+		}
+		if !matched {
+			return 0
+		}
+	}
+	score -= 5 * (last - ancestor)
+
+	if score <= 0 {
+		return 1
+	}
+	return score
+}
+
+func scoreName(query string, name string) int {
+	switch {
+	case query == name:
+		return 100
+	case strings.HasPrefix(name, query):
+		return 60
+	case strings.Contains(name, query):
+		return 40
+	case isSubsequence(query, name):
+		return 10
+	default:
+		return 0
+	}
+}
+
+func isSubsequence(query string, name string) bool {
+	remaining := []rune(query)
+	for _, r := range name {
+		if len(remaining) == 0 {
+			break
+		}
+		if r == remaining[0] {
+			remaining = remaining[1:]
+		}
+	}
+	return len(remaining) == 0
+}
+
+// normalizeName strips the index prefix and .md extension, lowercases and collapses separators
+func normalizeName(name string) string {
+	name = strings.ToLower(stripIndexPrefix(strings.TrimSpace(name)))
+	name = strings.TrimSuffix(name, ".md")
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// StripIndexes removes the index prefix from every component of a path, the result still resolves to the same entry
+func StripIndexes(entryPath string) string {
+	components := strings.Split(entryPath, string(filepath.Separator))
+	for i, component := range components {
+		components[i] = stripIndexPrefix(component)
+	}
+	return filepath.Join(components...)
+}
@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLoadEntryAtNeverFuzzyMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr error
+	}{
+		{name: "exact", query: "02. Projects", want: "02. Projects"},
+		{name: "without index", query: "Projects/Garden Logger.md", want: "02. Projects/01. Garden Logger.md"},
+		{name: "without extension", query: "Projects/Garden Logger", want: "02. Projects/01. Garden Logger.md"},
+		{name: "subsequence", query: "pj", wantErr: ErrNotFound},
+		{name: "prefix", query: "arc", wantErr: ErrNotFound},
+		{name: "contains", query: "Projects/Logger", wantErr: ErrNotFound},
+		{name: "wrong case", query: "projects", wantErr: ErrNotFound},
+		{name: "skipped level", query: "Garden Logger", wantErr: ErrNotFound},
+	}
+
+	s := newTestService(t, ".index", "01. Inbox/", "02. Projects/.index", "02. Projects/01. Garden Logger.md", "03. Archive/")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, entry, err := s.LoadEntryAt(tt.query)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("LoadEntryAt(%q) error = %v, want %v", tt.query, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadEntryAt(%q) error = %v", tt.query, err)
+			}
+			if got, _ := s.relPath(entry.FilePath()); got != tt.want {
+				t.Errorf("LoadEntryAt(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMutationsRejectNearMisses(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(s *EntryService) error
+	}{
+		{name: "rm", mutate: func(s *EntryService) error {
+			dir, entry, err := s.LoadEntryAt("arc")
+			if err != nil {
+				return err
+			}
+			return dir.DeleteEntryRecursive(entry)
+		}},
+		{name: "mv source", mutate: func(s *EntryService) error {
+			_, err := s.MoveEntry("dly", "Projects")
+			return err
+		}},
+		{name: "mv destination", mutate: func(s *EntryService) error {
+			_, err := s.MoveEntry("Inbox/Daily.md", "pj")
+			return err
+		}},
+		{name: "archive", mutate: func(s *EntryService) error {
+			_, err := s.ArchiveEntry("Inbox/Dai")
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, ".index", "01. Inbox/Daily.md", "02. Projects/.index", "03. Archive/Old.md")
+			s.config.ArchiveDir = "Archive"
+			before := gardenFiles(t, s)
+
+			if err := tt.mutate(s); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("error = %v, want %v", err, ErrNotFound)
+			}
+			if after := gardenFiles(t, s); !slices.Equal(before, after) {
+				t.Errorf("garden changed from %v to %v", before, after)
+			}
+		})
+	}
+}
+
+func TestResolveStillFuzzyMatches(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "pj", want: "02. Projects"},
+		{query: "arc", want: "03. Archive"},
+		{query: "garden logger", want: "02. Projects/01. Garden Logger.md"},
+		{query: "proj/logger", want: "02. Projects/01. Garden Logger.md"},
+	}
+
+	s := newTestService(t, ".index", "01. Inbox/", "02. Projects/.index", "02. Projects/01. Garden Logger.md", "03. Archive/")
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := s.ResolvePath(tt.query)
+			if err != nil {
+				t.Fatalf("ResolvePath(%q) error = %v", tt.query, err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolvePath(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreName(t *testing.T) {
+	tests := []struct {
+		query string
+		name  string
+		want  int
+	}{
+		{query: "log", name: "log", want: 100},
+		{query: "log", name: "logbook", want: 60},
+		{query: "log", name: "garden log", want: 40},
+		{query: "gl", name: "garden log", want: 10},
+		{query: "lg", name: "garden", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query+" in "+tt.name, func(t *testing.T) {
+			if got := scoreName(tt.query, tt.name); got != tt.want {
+				t.Errorf("scoreName(%q, %q) = %d, want %d", tt.query, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScorePath(t *testing.T) {
+	tests := []struct {
+		name       string
+		segments   []string
+		components []string
+		want       int
+	}{
+		{name: "top level", segments: []string{"log"}, components: []string{"log"}, want: 100},
+		{name: "each level deeper costs", segments: []string{"log"}, components: []string{"areas", "log"}, want: 95},
+		{name: "ancestor adds its score", segments: []string{"areas", "log"}, components: []string{"areas", "log"}, want: 200},
+		{name: "skipped ancestor costs", segments: []string{"areas", "log"}, components: []string{"areas", "journal", "log"}, want: 195},
+		{name: "ancestors in order", segments: []string{"log", "areas"}, components: []string{"areas", "log"}, want: 0},
+		{name: "unmatched ancestor", segments: []string{"work", "log"}, components: []string{"areas", "log"}, want: 0},
+		{name: "weak deep match keeps a score", segments: []string{"gl"}, components: []string{"a", "b", "c", "garden log"}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scorePath(tt.segments, tt.components); got != tt.want {
+				t.Errorf("scorePath(%q, %q) = %d, want %d", tt.segments, tt.components, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTieBreaking(t *testing.T) {
+	garden := []string{"Log.md", "Logbook.md", "Areas/Daily.md", "Projects/Daily.md"}
+
+	t.Run("better tier wins", func(t *testing.T) {
+		s := newTestService(t, garden...)
+		if got, err := s.ResolvePath("log"); err != nil || got != "Log.md" {
+			t.Errorf("ResolvePath() = %q, %v, want %q", got, err, "Log.md")
+		}
+	})
+
+	t.Run("tie is ambiguous", func(t *testing.T) {
+		s := newTestService(t, garden...)
+		_, err := s.ResolvePath("daily")
+		var ambiguous *AmbiguousPathError
+		if !errors.As(err, &ambiguous) {
+			t.Fatalf("ResolvePath() error = %v, want an AmbiguousPathError", err)
+		}
+		if want := []string{"Areas/Daily.md", "Projects/Daily.md"}; !slices.Equal(ambiguous.Candidates, want) {
+			t.Errorf("Candidates = %v, want %v", ambiguous.Candidates, want)
+		}
+	})
+
+	t.Run("frecency breaks the tie", func(t *testing.T) {
+		s := newTestService(t, garden...)
+		s.RecordVisit("Projects/Daily.md")
+		if got, err := s.ResolvePath("daily"); err != nil || got != "Projects/Daily.md" {
+			t.Errorf("ResolvePath() = %q, %v, want %q", got, err, "Projects/Daily.md")
+		}
+	})
+
+	t.Run("picker breaks the tie", func(t *testing.T) {
+		s := newTestService(t, garden...)
+		var offered []string
+		s.SetPicker(func(query string, candidates []string) (string, error) {
+			offered = candidates
+			return candidates[1], nil
+		})
+		if got, err := s.ResolvePath("daily"); err != nil || got != "Projects/Daily.md" {
+			t.Errorf("ResolvePath() = %q, %v, want %q", got, err, "Projects/Daily.md")
+		}
+		if len(offered) != 2 {
+			t.Errorf("picker was offered %v, want both notes", offered)
+		}
+	})
+}
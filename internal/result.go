@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Outcome says how an operation ended when it didn't fail
+type Outcome int
+
+const (
+	OutcomeNoop Outcome = iota
+	OutcomeDone
+	OutcomeCreated
+	OutcomeLaunched
+	OutcomeCancelled
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeNoop:
+		return "noop"
+	case OutcomeDone:
+		return "done"
+	case OutcomeCreated:
+		return "created"
+	case OutcomeLaunched:
+		return "launched"
+	case OutcomeCancelled:
+		return "cancelled"
+	default:
+		return ""
+	}
+}
+
+// Result is what Browse and the CLI commands hand back to their entry point, Path is relative to the root
+type Result struct {
+	Outcome Outcome
+	Path    string
+}
+
+var (
+	// ErrEntryExists is returned when creation is aborted because the target already exists
+	ErrEntryExists = errors.New("entry already exists")
+	// ErrNotFound is returned when a path doesn't resolve to an entry
+	ErrNotFound = errors.New("not found")
+	// ErrIndexConflict is returned when an operation needs indexing the directory doesn't have
+	ErrIndexConflict = errors.New("index conflict")
+	// ErrValidation is returned for invalid input and directories whose indexing doesn't validate
+	ErrValidation = errors.New("validation failed")
+	// ErrConfig is returned when the config can't be read or is incomplete
+	ErrConfig = errors.New("config error")
+	// ErrUsage is returned when a command is called with the wrong arguments
+	ErrUsage = errors.New("usage")
+	// ErrReadOnly is returned when something tries to change the garden in read-only mode
+	ErrReadOnly = errors.New("read-only mode")
+	// ErrCancelled is returned when the user dismisses a menu or prompt
+	ErrCancelled = errors.New("cancelled")
+)
+
+// Exit codes shared by both entry points, documented in the README
+const (
+	ExitOK            = 0
+	ExitError         = 1
+	ExitUsage         = 2
+	ExitCancelled     = 3
+	ExitNotFound      = 4
+	ExitAlreadyExists = 5
+	ExitAmbiguous     = 6
+	ExitIndexConflict = 7
+	ExitValidation    = 8
+	ExitConfig        = 9
+	ExitReadOnly      = 10
+)
+
+// ExitCode maps the outcome of a run to the process exit code
+func ExitCode(result Result, err error) int {
+	switch {
+	case err == nil && result.Outcome == OutcomeCancelled:
+		return ExitCancelled
+	case err == nil:
+		return ExitOK
+	default:
+		return ErrorExitCode(err)
+	}
+}
+
+// ErrorExitCode classifies an error, the JSON error codes are derived from it so both always agree
+func ErrorExitCode(err error) int {
+	var ambiguousErr *AmbiguousPathError
+	switch {
+	case errors.Is(err, ErrCancelled):
+		return ExitCancelled
+	case errors.As(err, &ambiguousErr):
+		return ExitAmbiguous
+	case errors.Is(err, ErrUsage):
+		return ExitUsage
+	case errors.Is(err, ErrConfig):
+		return ExitConfig
+	case errors.Is(err, ErrReadOnly):
+		return ExitReadOnly
+	case errors.Is(err, ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		return ExitNotFound
+	case errors.Is(err, ErrEntryExists), errors.Is(err, fs.ErrExist):
+		return ExitAlreadyExists
+	case errors.Is(err, ErrIndexConflict):
+		return ExitIndexConflict
+	case errors.Is(err, ErrValidation):
+		return ExitValidation
+	default:
+		return ExitError
+	}
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+)
+
+// DefaultFolders is the PARA skeleton init creates, the first folder becomes the inbox and the last the archive
+var DefaultFolders = []string{"Inbox", "Projects", "Areas", "Resources", "Archive"}
+
+// starterTemplates seed a new template directory, RenderTemplate adds the title heading
+var starterTemplates = []struct {
+	name    string
+	content string
+}{
+	{"Daily", "## Tasks\n\n## Notes\n\n## Log\n"},
+	{"Weekly", "Week of {{date}}\n\n## Goals\n\n## Review\n"},
+	{"Meeting", "{{date}} {{time}}\n\n## Attendees\n\n## Notes\n\n## Actions\n"},
+	{"Project", "Started {{date}}\n\n## Goal\n\n## Tasks\n\n## Resources\n"},
+}
+
+// InitGarden scaffolds an indexed PARA garden at the service's root and points its config's directories at it.
+// Running it again only fills in what's missing. A non-empty root that isn't an indexed garden yet is refused unless force is set.
+func InitGarden(notes *EntryService, folders []string, force bool) error {
+	if len(folders) == 0 {
+		folders = DefaultFolders
+	}
+
+	config := notes.config
+	rootDir := config.RootDir
+	entries, err := notes.files.readDir(rootDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read root %s: %w", rootDir, err)
+	}
+	if len(entries) > 0 && !notes.files.isIndexed(rootDir) && !force {
+		return fmt.Errorf("%w: %s is not empty and not a garden, use --force to scaffold into it anyway", ErrEntryExists, rootDir)
+	}
+
+	if err := notes.files.mkdirAll(rootDir); err != nil {
+		return fmt.Errorf("failed to create root %s: %w", rootDir, err)
+	}
+
+	if err := indexDirectory(notes, ""); err != nil {
+		return err
+	}
+
+	var folderPaths []string
+	for _, folder := range folders {
+		dir, err := notes.EnsureDirectory(folder)
+		if err != nil {
+			return err
+		}
+		if err := indexDirectory(notes, dir.Path); err != nil {
+			return err
+		}
+		folderPaths = append(folderPaths, folder)
+	}
+
+	// Entries that were already there may have shifted the new folders' indexes, so resolve them once everything exists
+	if err := indexDirectory(notes, ""); err != nil {
+		return err
+	}
+	for i, folder := range folderPaths {
+		if folderPaths[i], err = notes.ResolveDir(folder); err != nil {
+			return err
+		}
+	}
+
+	oldInbox := config.InboxDir
+	config.InboxDir = folderPaths[0]
+	config.ArchiveDir = folderPaths[len(folderPaths)-1]
+
+	templateDir, err := notes.EnsureDirectory(filepath.Join(config.ArchiveDir, "Templates"))
+	if err != nil {
+		return err
+	}
+	if err := indexDirectory(notes, templateDir.Path); err != nil {
+		return err
+	}
+	config.TemplateDir = templateDir.Path
+
+	for _, template := range starterTemplates {
+		templateDir, err := notes.LoadDirectory(config.TemplateDir)
+		if err != nil {
+			return err
+		}
+		entry := &Entry{Name: template.name, Ext: ".md", EntryIndex: templateDir.NewFileIndex()}
+		content := func(string) string { return template.content }
+		if _, err := notes.createEntry(templateDir, entry, content, CollisionOpen); err != nil {
+			return err
+		}
+	}
+
+	// Notes that lived in the old inbox follow it to the new one
+	for _, path := range []*string{&config.Daily.Path, &config.Weekly.Path, &config.Monthly.Path,
+		&config.Quarterly.Path, &config.Yearly.Path, &config.Capture.Path} {
+		if filepath.Dir(*path) == oldInbox {
+			*path = filepath.Join(config.InboxDir, filepath.Base(*path))
+		}
+	}
+	if config.Daily.Template == "" {
+		config.Daily.Template = "Daily"
+	}
+	if config.Weekly.Template == "" {
+		config.Weekly.Template = "Weekly"
+	}
+
+	slog.Info("Initialized garden", "root", rootDir, "folders", folderPaths, "templates", config.TemplateDir)
+	return nil
+}
+
+// indexDirectory marks a directory as indexed and renumbers its entries
+func indexDirectory(notes *EntryService, dirPath string) error {
+	dir, err := notes.LoadDirectory(dirPath)
+	if err != nil {
+		return err
+	}
+	if dir.IsIndexed && dir.ValidateIndexing() == nil {
+		return nil
+	}
+	return dir.ApplyNumericIndexing()
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type SearchOptions struct {
+	// Phrase matches the query as one piece of text instead of every word on its own
+	Phrase bool
+	// Regex treats the query as a regular expression
+	Regex bool
+	// CaseSensitive stops letter case from being ignored
+	CaseSensitive bool
+	// Limit stops the search after that many hits, 0 means no limit
+	Limit int
+}
+
+// SearchHit is one matching line, Path is relative to the root and Line starts at 1
+type SearchHit struct {
+	Path    string
+	Line    int
+	Snippet string
+}
+
+func (h SearchHit) String() string {
+	return fmt.Sprintf("%s:%d: %s", h.Path, h.Line, h.Snippet)
+}
+
+const snippetLength = 120
+
+// ParseSearchQuery reads the options from how a query typed into the menu is written:
+// "quoted" text is a phrase, /slashed/ text a regex, and any capital letter makes it case sensitive
+func ParseSearchQuery(text string) (string, SearchOptions) {
+	query := strings.TrimSpace(text)
+	var opts SearchOptions
+
+	switch {
+	case len(query) > 2 && strings.HasPrefix(query, `"`) && strings.HasSuffix(query, `"`):
+		query = query[1 : len(query)-1]
+		opts.Phrase = true
+	case len(query) > 2 && strings.HasPrefix(query, "/") && strings.HasSuffix(query, "/"):
+		query = query[1 : len(query)-1]
+		opts.Regex = true
+	}
+	opts.CaseSensitive = strings.IndexFunc(query, unicode.IsUpper) >= 0
+	return query, opts
+}
+
+// compileSearch turns a query into a function that reports whether a line matches it
+func compileSearch(query string, opts SearchOptions) (func(line string) bool, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("%w: nothing to search for", ErrValidation)
+	}
+
+	if opts.Regex {
+		pattern := query
+		if !opts.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid regex: %w", ErrValidation, err)
+		}
+		return re.MatchString, nil
+	}
+
+	fold := func(s string) string { return s }
+	if !opts.CaseSensitive {
+		fold = strings.ToLower
+	}
+
+	words := strings.Fields(fold(query))
+	if opts.Phrase {
+		words = []string{fold(query)}
+	}
+	return func(line string) bool {
+		line = fold(line)
+		for _, word := range words {
+			if !strings.Contains(line, word) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// Search finds the lines matching query. Notes come ranked by the search index, best first, and a regex
+// query, or one without any words to look up, falls back to scanning every note in garden order.
+func (s *EntryService) Search(query string, opts SearchOptions) ([]SearchHit, error) {
+	match, err := compileSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	words := tokenize(query)
+	if opts.Regex || len(words) == 0 {
+		return s.scanSearch(match, opts.Limit)
+	}
+
+	idx, _, err := s.UpdateSearchIndex(false)
+	if idx == nil {
+		slog.Debug("Search index unavailable, scanning the garden", "error", err)
+		return s.scanSearch(match, opts.Limit)
+	}
+	if err != nil {
+		slog.Debug("Failed to save search index", "error", err)
+	}
+
+	var hits []SearchHit
+	for _, doc := range idx.query(words) {
+		content, err := s.files.readFile(filepath.Join(s.config.RootDir, doc.path))
+		if err != nil {
+			slog.Debug("Skipping unreadable note", "path", doc.path, "error", err)
+			continue
+		}
+		lines := strings.Split(string(content), "\n")
+
+		// The index ignores case and punctuation, so phrases and case sensitive queries are checked line by line
+		var lineNumbers []int
+		if opts.Phrase || opts.CaseSensitive {
+			for i, line := range lines {
+				if match(line) {
+					lineNumbers = append(lineNumbers, i+1)
+				}
+			}
+		} else {
+			lineNumbers = idx.lines(doc.path, words)
+		}
+
+		for _, n := range lineNumbers {
+			if n > len(lines) {
+				continue
+			}
+			hits = append(hits, SearchHit{Path: doc.path, Line: n, Snippet: snippet(lines[n-1])})
+			if opts.Limit > 0 && len(hits) >= opts.Limit {
+				return hits, nil
+			}
+		}
+	}
+
+	slog.Debug("Searched garden", "query", query, "hits", len(hits))
+	return hits, nil
+}
+
+// scanSearch reads every note that isn't ignored and returns the lines match accepts, in garden order
+func (s *EntryService) scanSearch(match func(line string) bool, limit int) ([]SearchHit, error) {
+	var hits []SearchHit
+	errLimit := fmt.Errorf("search limit reached")
+	err := s.walkNotes(func(entryPath string, entry *Entry) error {
+		content, err := s.files.readFile(entry.FilePath())
+		if err != nil {
+			slog.Debug("Skipping unreadable note", "path", entryPath, "error", err)
+			return nil
+		}
+		if bytes.IndexByte(content, 0) >= 0 {
+			return nil
+		}
+
+		for i, line := range strings.Split(string(content), "\n") {
+			if !match(line) {
+				continue
+			}
+			hits = append(hits, SearchHit{Path: entryPath, Line: i + 1, Snippet: snippet(line)})
+			if limit > 0 && len(hits) >= limit {
+				return errLimit
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errLimit {
+		return nil, err
+	}
+
+	slog.Debug("Scanned garden", "hits", len(hits))
+	return hits, nil
+}
+
+// snippet trims a matching line down to something that fits on one menu row
+func snippet(line string) string {
+	line = strings.TrimSpace(line)
+	runes := []rune(line)
+	if len(runes) > snippetLength {
+		return string(runes[:snippetLength]) + "…"
+	}
+	return line
+}
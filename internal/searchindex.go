@@ -0,0 +1,345 @@
+package internal
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// searchIndexVersion is bumped whenever the file format or tokenizing changes, older indexes are rebuilt
+const searchIndexVersion = 4
+
+// BM25 parameters, prefixWeight scales matches on longer words that only start with a query word
+const (
+	bm25K1       = 1.2
+	bm25B        = 0.75
+	prefixWeight = 0.5
+)
+
+// SearchIndex maps every word in the garden to the notes and lines it appears on.
+// Notes are keyed by their path relative to the root and only reread when their mtime or size changes.
+type SearchIndex struct {
+	Version  int                         `json:"version"`
+	Root     string                      `json:"root"`
+	Docs     map[string]*indexedDoc      `json:"docs"`
+	Postings map[string]map[string][]int `json:"postings"`
+
+	path string
+	// Sorted keys of Postings for prefix lookups, nil until a query needs them
+	terms []string
+}
+
+type indexedDoc struct {
+	ModTime int64    `json:"mtime"`
+	Size    int64    `json:"size"`
+	Length  int      `json:"length"`
+	Terms   []string `json:"terms"`
+	Tags    []string `json:"tags,omitempty"`
+	Links   []Link   `json:"links,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// IndexStats describes what an update of the search index did
+type IndexStats struct {
+	Notes   int
+	Updated int
+	Removed int
+	Terms   int
+}
+
+type rankedDoc struct {
+	path  string
+	score float64
+}
+
+func newSearchIndex(path string, root string) *SearchIndex {
+	return &SearchIndex{
+		Version:  searchIndexVersion,
+		Root:     root,
+		Docs:     map[string]*indexedDoc{},
+		Postings: map[string]map[string][]int{},
+		path:     path,
+	}
+}
+
+func searchIndexPath(root string) (string, error) {
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "search", rootFileName(root)), nil
+}
+
+// loadSearchIndex reads the index at path, starting over when it's missing, unreadable or from another version
+func loadSearchIndex(path string, root string) *SearchIndex {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Debug("Failed to read search index, rebuilding it", "path", path, "error", err)
+		}
+		return newSearchIndex(path, root)
+	}
+
+	idx := &SearchIndex{}
+	if err := json.Unmarshal(data, idx); err != nil || idx.Version != searchIndexVersion || idx.Root != root {
+		slog.Debug("Search index is stale, rebuilding it", "path", path, "error", err)
+		return newSearchIndex(path, root)
+	}
+	idx.path = path
+	return idx
+}
+
+func (idx *SearchIndex) save() error {
+	return saveJSON(idx.path, idx)
+}
+
+// UpdateSearchIndex brings the search index up to date with the garden, rereading only notes that changed.
+// The index is still returned when only saving it failed. Dry runs update it in memory without saving.
+func (s *EntryService) UpdateSearchIndex(rebuild bool) (*SearchIndex, IndexStats, error) {
+	path, err := searchIndexPath(s.config.RootDir)
+	if err != nil {
+		return nil, IndexStats{}, err
+	}
+
+	idx := newSearchIndex(path, s.config.RootDir)
+	if !rebuild {
+		idx = loadSearchIndex(path, s.config.RootDir)
+	}
+
+	var stats IndexStats
+	seen := map[string]bool{}
+	err = s.walkNotes(func(entryPath string, entry *Entry) error {
+		info, err := os.Stat(entry.FilePath())
+		if err != nil {
+			return nil
+		}
+		seen[entryPath] = true
+
+		doc, ok := idx.Docs[entryPath]
+		if ok && doc.ModTime == info.ModTime().UnixNano() && doc.Size == info.Size() {
+			return nil
+		}
+
+		content, err := s.files.readFile(entry.FilePath())
+		if err != nil {
+			slog.Debug("Skipping unreadable note", "path", entryPath, "error", err)
+			delete(seen, entryPath)
+			return nil
+		}
+		idx.remove(entryPath)
+		idx.add(entryPath, info, content)
+		stats.Updated++
+		return nil
+	})
+	if err != nil {
+		return nil, IndexStats{}, err
+	}
+
+	for docPath := range idx.Docs {
+		if !seen[docPath] {
+			idx.remove(docPath)
+			stats.Removed++
+		}
+	}
+
+	stats.Notes = len(idx.Docs)
+	stats.Terms = len(idx.Postings)
+	slog.Debug("Updated search index", "path", path, "notes", stats.Notes, "updated", stats.Updated, "removed", stats.Removed)
+
+	if (rebuild || stats.Updated > 0 || stats.Removed > 0) && !s.IsDryRun() {
+		if err := idx.save(); err != nil {
+			return idx, stats, err
+		}
+	}
+	return idx, stats, nil
+}
+
+func (idx *SearchIndex) add(docPath string, info fs.FileInfo, content []byte) {
+	doc := &indexedDoc{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+	idx.Docs[docPath] = doc
+	idx.terms = nil
+
+	// Binary files are remembered so they aren't reread, but have nothing to find
+	if bytes.IndexByte(content, 0) >= 0 {
+		return
+	}
+	annotateDoc(doc, docPath, content)
+
+	for i, line := range strings.Split(string(content), "\n") {
+		for _, term := range tokenize(line) {
+			postings, ok := idx.Postings[term]
+			if !ok {
+				postings = map[string][]int{}
+				idx.Postings[term] = postings
+			}
+			if _, ok := postings[docPath]; !ok {
+				doc.Terms = append(doc.Terms, term)
+			}
+			postings[docPath] = append(postings[docPath], i+1)
+			doc.Length++
+		}
+	}
+}
+
+// annotateDoc remembers the tags, links and aliases of a note so they don't need a walk over the garden of their own
+func annotateDoc(doc *indexedDoc, docPath string, content []byte) {
+	if filepath.Ext(docPath) != ".md" {
+		return
+	}
+	doc.Tags = ParseTags(string(content))
+	doc.Links = ParseLinks(string(content))
+	if meta, err := ParseFrontmatter(string(content)); err == nil {
+		doc.Aliases = meta.Aliases()
+	}
+}
+
+// indexedDocs returns what the search index knows about every note, scanning the garden when it can't be loaded
+func (s *EntryService) indexedDocs() (map[string]*indexedDoc, error) {
+	idx, _, err := s.UpdateSearchIndex(false)
+	if idx != nil {
+		if err != nil {
+			slog.Debug("Failed to save search index", "error", err)
+		}
+		return idx.Docs, nil
+	}
+
+	slog.Debug("Search index unavailable, scanning the garden", "error", err)
+	docs := map[string]*indexedDoc{}
+	err = s.walkNotes(func(notePath string, entry *Entry) error {
+		content, err := s.files.readFile(entry.FilePath())
+		if err != nil {
+			slog.Debug("Skipping unreadable note", "path", notePath, "error", err)
+			return nil
+		}
+		doc := &indexedDoc{}
+		annotateDoc(doc, notePath, content)
+		docs[notePath] = doc
+		return nil
+	})
+	return docs, err
+}
+
+func (idx *SearchIndex) remove(docPath string) {
+	doc, ok := idx.Docs[docPath]
+	if !ok {
+		return
+	}
+	for _, term := range doc.Terms {
+		delete(idx.Postings[term], docPath)
+		if len(idx.Postings[term]) == 0 {
+			delete(idx.Postings, term)
+		}
+	}
+	delete(idx.Docs, docPath)
+	idx.terms = nil
+}
+
+// tokenize splits text into lowercase words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// expand returns every indexed term starting with word, word itself first when it's indexed
+func (idx *SearchIndex) expand(word string) []string {
+	if idx.terms == nil {
+		idx.terms = make([]string, 0, len(idx.Postings))
+		for term := range idx.Postings {
+			idx.terms = append(idx.terms, term)
+		}
+		sort.Strings(idx.terms)
+	}
+
+	var terms []string
+	for i := sort.SearchStrings(idx.terms, word); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], word); i++ {
+		terms = append(terms, idx.terms[i])
+	}
+	return terms
+}
+
+// query ranks the notes containing every word, as a whole word or a prefix, by BM25
+func (idx *SearchIndex) query(words []string) []rankedDoc {
+	if len(idx.Docs) == 0 {
+		return nil
+	}
+
+	totalLength := 0
+	for _, doc := range idx.Docs {
+		totalLength += doc.Length
+	}
+	docCount := float64(len(idx.Docs))
+	avgLength := math.Max(float64(totalLength)/docCount, 1)
+
+	var scores map[string]float64
+	for _, word := range words {
+		matched := map[string]float64{}
+		for _, term := range idx.expand(word) {
+			weight := 1.0
+			if term != word {
+				weight = prefixWeight
+			}
+
+			postings := idx.Postings[term]
+			df := float64(len(postings))
+			idf := math.Log(1 + (docCount-df+0.5)/(df+0.5))
+			for docPath, lines := range postings {
+				tf := float64(len(lines))
+				norm := 1 - bm25B + bm25B*float64(idx.Docs[docPath].Length)/avgLength
+				matched[docPath] += weight * idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			}
+		}
+
+		if scores == nil {
+			scores = matched
+			continue
+		}
+		for docPath := range scores {
+			if score, ok := matched[docPath]; ok {
+				scores[docPath] += score
+			} else {
+				delete(scores, docPath)
+			}
+		}
+	}
+
+	ranked := make([]rankedDoc, 0, len(scores))
+	for docPath, score := range scores {
+		ranked = append(ranked, rankedDoc{docPath, score})
+	}
+	slices.SortFunc(ranked, func(a, b rankedDoc) int {
+		if order := cmp.Compare(b.score, a.score); order != 0 {
+			return order
+		}
+		return strings.Compare(a.path, b.path)
+	})
+	return ranked
+}
+
+// lines returns the sorted line numbers in a note where every word appears, like a scan matches them
+func (idx *SearchIndex) lines(docPath string, words []string) []int {
+	var lines []int
+	for i, word := range words {
+		var wordLines []int
+		for _, term := range idx.expand(word) {
+			wordLines = append(wordLines, idx.Postings[term][docPath]...)
+		}
+		if i == 0 {
+			lines = wordLines
+			continue
+		}
+		lines = slices.DeleteFunc(lines, func(line int) bool { return !slices.Contains(wordLines, line) })
+	}
+	slices.Sort(lines)
+	return slices.Compact(lines)
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeNote(t *testing.T, s *EntryService, notePath string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(s.config.RootDir, notePath), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateSearchIndexIsIncremental(t *testing.T) {
+	s := newTestService(t, "a.md", "b.md", "Dir/c.md")
+
+	steps := []struct {
+		name   string
+		change func(t *testing.T)
+		want   IndexStats
+	}{
+		{
+			name:   "first update reads every note",
+			change: func(t *testing.T) {},
+			want:   IndexStats{Notes: 3, Updated: 3},
+		},
+		{
+			name:   "nothing changed",
+			change: func(t *testing.T) {},
+			want:   IndexStats{Notes: 3},
+		},
+		{
+			name:   "edited note",
+			change: func(t *testing.T) { writeNote(t, s, "b.md", "# b\n\nlonger now\n") },
+			want:   IndexStats{Notes: 3, Updated: 1},
+		},
+		{
+			name: "added and removed notes",
+			change: func(t *testing.T) {
+				writeNote(t, s, "d.md", "# d\n")
+				if err := os.Remove(filepath.Join(s.config.RootDir, "Dir/c.md")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: IndexStats{Notes: 3, Updated: 1, Removed: 1},
+		},
+	}
+
+	for _, step := range steps {
+		step.change(t)
+		_, stats, err := s.UpdateSearchIndex(false)
+		if err != nil {
+			t.Fatalf("%s: UpdateSearchIndex() error = %v", step.name, err)
+		}
+		stats.Terms = 0
+		if stats != step.want {
+			t.Errorf("%s: stats = %+v, want %+v", step.name, stats, step.want)
+		}
+	}
+
+	idx, stats, err := s.UpdateSearchIndex(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Updated != 3 {
+		t.Errorf("rebuild updated %d notes, want 3", stats.Updated)
+	}
+	if got := idx.query([]string{"longer"}); len(got) != 1 || got[0].path != "b.md" {
+		t.Errorf("query after edit = %v, want b.md", got)
+	}
+	if _, ok := idx.Postings["c"]; ok {
+		t.Error("the removed note's words are still indexed")
+	}
+}
+
+func TestSearchIndexQuery(t *testing.T) {
+	s := newTestService(t)
+	writeNote(t, s, "garden.md", "# Garden\n\nwater the tomatoes\nplant tomatoes and basil\n")
+	writeNote(t, s, "kitchen.md", "# Kitchen\n\nbasil pesto\ntomato soup\n")
+	writeNote(t, s, "log.md", "# Log\n\nwatered everything\n")
+
+	idx, _, err := s.UpdateSearchIndex(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		words     []string
+		wantDocs  []string
+		wantLines map[string][]int
+	}{
+		{
+			name:      "every word must appear",
+			words:     []string{"tomatoes", "basil"},
+			wantDocs:  []string{"garden.md"},
+			wantLines: map[string][]int{"garden.md": {4}},
+		},
+		{
+			name:      "words may be on different lines",
+			words:     []string{"basil", "soup"},
+			wantDocs:  []string{"kitchen.md"},
+			wantLines: map[string][]int{"kitchen.md": nil},
+		},
+		{
+			name:      "prefixes match longer words",
+			words:     []string{"water"},
+			wantDocs:  []string{"garden.md", "log.md"},
+			wantLines: map[string][]int{"garden.md": {3}, "log.md": {3}},
+		},
+		{
+			name:      "whole words rank above prefixes",
+			words:     []string{"tomato"},
+			wantDocs:  []string{"kitchen.md", "garden.md"},
+			wantLines: map[string][]int{"kitchen.md": {4}, "garden.md": {3, 4}},
+		},
+		{
+			name:     "no match",
+			words:    []string{"basil", "watered"},
+			wantDocs: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := []string{}
+			for _, doc := range idx.query(tt.words) {
+				docs = append(docs, doc.path)
+			}
+			if !slices.Equal(docs, tt.wantDocs) {
+				t.Errorf("query(%q) = %v, want %v", tt.words, docs, tt.wantDocs)
+			}
+			for docPath, want := range tt.wantLines {
+				if got := idx.lines(docPath, tt.words); !slices.Equal(got, want) {
+					t.Errorf("lines(%q, %q) = %v, want %v", docPath, tt.words, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateSearchIndexDryRunDoesNotSave(t *testing.T) {
+	s := newTestService(t, "a.md")
+	s.SetWriteMode(WriteDryRun)
+
+	if _, stats, err := s.UpdateSearchIndex(false); err != nil || stats.Updated != 1 {
+		t.Fatalf("UpdateSearchIndex() = %+v, %v", stats, err)
+	}
+	path, err := searchIndexPath(s.config.RootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("dry run saved the index, stat error = %v", err)
+	}
+}
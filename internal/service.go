@@ -1,39 +1,60 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
-// LaunchSuccessError signals that the application should exit after successful launch
-type LaunchSuccessError struct {
-	Message string
-}
-
-func (e LaunchSuccessError) Error() string { return e.Message }
-
 // EntryService handles all note and directory operations
 type EntryService struct {
 	config *Config
+	picker Picker
+	files  *FileWriter
 }
 
+// NewNotesService starts in read-only mode when the config asks for it, SetWriteMode changes that
 func NewNotesService(config *Config) *EntryService {
-	return &EntryService{config: config}
+	mode := WriteEnabled
+	if config.ReadOnly {
+		mode = WriteReadOnly
+	}
+	return &EntryService{config: config, files: NewFileWriter(mode)}
+}
+
+func (s *EntryService) SetWriteMode(mode WriteMode) {
+	s.files.SetMode(mode)
+}
+
+func (s *EntryService) IsDryRun() bool {
+	return s.files.IsDryRun()
+}
+
+func (s *EntryService) IsReadOnly() bool {
+	return s.files.IsReadOnly()
+}
+
+// TakeChanges returns the changes made, or planned in a dry run, since the last call and clears them
+func (s *EntryService) TakeChanges() []Change {
+	return s.files.TakeChanges()
 }
 
 func (s *EntryService) LoadDirectory(dirPath string) (*Directory, error) {
 	absPath := filepath.Join(s.config.RootDir, dirPath)
 
-	isIndexed := LoadIsIndexed(absPath)
+	isIndexed := s.files.isIndexed(absPath)
 	dir := &Directory{
 		Path:      dirPath,
 		AbsPath:   absPath,
 		IsIndexed: isIndexed,
 		Entries:   nil,
+		files:     s.files,
 	}
 
 	err := dir.LoadEntries()
@@ -46,35 +67,185 @@ func (s *EntryService) LoadDirectory(dirPath string) (*Directory, error) {
 	return dir, nil
 }
 
+// LoadEntryAt finds the entry at a path relative to the root, indexes may be left out, and returns it along with
+// its parent directory. It doesn't fall back to fuzzy matching since its callers move, rename or delete the entry.
+func (s *EntryService) LoadEntryAt(entryPath string) (*Directory, *Entry, error) {
+	resolved, err := s.resolveExact(entryPath, kindAny)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.loadEntry(resolved)
+}
+
+// loadEntry loads the entry at an exact path relative to the root
+func (s *EntryService) loadEntry(entryPath string) (*Directory, *Entry, error) {
+	if entryPath == "" {
+		return nil, nil, fmt.Errorf("the root directory is not an entry")
+	}
+
+	parentPath := filepath.Dir(entryPath)
+	if parentPath == "." {
+		parentPath = ""
+	}
+
+	dir, err := s.LoadDirectory(parentPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry := dir.GetEntryByFilename(filepath.Base(entryPath))
+	if entry == nil {
+		return nil, nil, fmt.Errorf("entry %w: %s", ErrNotFound, entryPath)
+	}
+	return dir, entry, nil
+}
+
+// MoveEntry moves an entry into another directory, taking the next free index there and closing its gap at the source
+func (s *EntryService) MoveEntry(entryPath string, destPath string) (string, error) {
+	srcDir, entry, err := s.LoadEntryAt(entryPath)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedDest, err := s.resolveExact(destPath, kindDir)
+	if err != nil {
+		return "", err
+	}
+
+	destDir, err := s.LoadDirectory(resolvedDest)
+	if err != nil {
+		return "", err
+	}
+
+	oldPath := entry.FilePath()
+	if destDir.AbsPath == srcDir.AbsPath {
+		return s.relPath(oldPath)
+	}
+	if entry.IsDir && strings.HasPrefix(destDir.AbsPath+string(filepath.Separator), oldPath+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: cannot move %s into itself", ErrValidation, entryPath)
+	}
+	if existing := destDir.GetEntryByName(entry.Name, entry.Ext); existing != nil {
+		return "", fmt.Errorf("%w: %s", ErrEntryExists, existing.FilePath())
+	}
+
+	moved := &Entry{
+		Name:       entry.Name,
+		EntryIndex: -1,
+		Ext:        entry.Ext,
+		IsDir:      entry.IsDir,
+		ParentPath: destDir.AbsPath,
+	}
+	if destDir.IsIndexed {
+		moved.EntryIndex = destDir.NewFileIndex()
+		if moved.IsDir {
+			moved.EntryIndex = destDir.NewDirIndex()
+		}
+	}
+
+	// Inserting renumbers the destination first, undo that too if the move fails halfway
+	slog.Debug("Moving entry", "from", oldPath, "to", moved.FilePath())
+	tx := s.files.BeginTransaction()
+	if err := destDir.InsertEntry(moved); err != nil {
+		return "", tx.Abort(err)
+	}
+	if err := s.files.rename(oldPath, moved.FilePath()); err != nil {
+		return "", tx.Abort(fmt.Errorf("failed to move %s: %w", entryPath, err))
+	}
+	if err := srcDir.DetachEntry(entry); err != nil {
+		return "", tx.Abort(err)
+	}
+	tx.Commit()
+
+	return s.relPath(moved.FilePath())
+}
+
+// ArchiveEntry moves an entry into the archive directory
+func (s *EntryService) ArchiveEntry(entryPath string) (string, error) {
+	if _, err := s.EnsureDirectory(s.config.ArchiveDir); err != nil {
+		return "", err
+	}
+	return s.MoveEntry(entryPath, s.config.ArchiveDir)
+}
+
+// ListTemplates returns every template note in the template directory
+func (s *EntryService) ListTemplates() ([]*Entry, error) {
+	dir, err := s.LoadDirectory(s.config.TemplateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []*Entry
+	for _, entry := range dir.Entries {
+		if !entry.IsDir {
+			templates = append(templates, entry)
+		}
+	}
+	return templates, nil
+}
+
 func (s *EntryService) CreateEntry(d *Directory, entry *Entry) (string, error) {
-	slog.Debug("Creating entry", "name", entry.Name, "index", entry.EntryIndex, "isDir", entry.IsDir, "parentPath", entry.ParentPath)
+	d, err := s.noteDirectory(d, entry)
+	if err != nil {
+		return "", err
+	}
+	return s.createEntry(d, entry, titleHeading, s.config.OnCollision)
+}
 
-	var targetDir string
+// noteDirectory sends notes created at the root to the inbox, directories and notes elsewhere stay where they are
+func (s *EntryService) noteDirectory(d *Directory, entry *Entry) (*Directory, error) {
+	if d.Path != "" || entry.IsDir {
+		return d, nil
+	}
 
-	if d.Path == "" && !entry.IsDir {
-		targetDir = filepath.Join(s.config.RootDir, s.config.InboxDir)
-	} else {
-		targetDir = d.AbsPath
+	inbox, err := s.LoadDirectory(s.config.InboxDir)
+	if err != nil {
+		return nil, err
 	}
+	if entry.EntryIndex != -1 {
+		entry.EntryIndex = inbox.NewFileIndex()
+	}
+	return inbox, nil
+}
 
-	fullPath := filepath.Join(targetDir, entry.String())
-	slog.Debug("Creating at path", "fullPath", fullPath, "targetDir", targetDir)
+func titleHeading(name string) string {
+	return fmt.Sprintf("# %s\n\n", name)
+}
 
-	if entry.IsDir {
-		err := os.Mkdir(fullPath, 0755)
-		if err != nil {
-			return "", fmt.Errorf("failed to create note directory %s: %w", fullPath, err)
+// createEntry writes a new entry to disk, resolving name collisions with the configured policy.
+// Files are opened with O_EXCL so concurrent invocations can't truncate each other's notes.
+// content is called with the final name, after any collision suffix, and is ignored for directories.
+// The returned path is relative to the root directory.
+func (s *EntryService) createEntry(d *Directory, entry *Entry, content func(name string) string, policy CollisionPolicy) (string, error) {
+	slog.Debug("Creating entry", "name", entry.Name, "index", entry.EntryIndex, "isDir", entry.IsDir, "policy", policy)
+
+	entry.ParentPath = d.AbsPath
+
+	baseName := entry.Name
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			entry.Name = fmt.Sprintf("%s (%d)", baseName, attempt)
 		}
-	} else {
-		file, err := os.Create(fullPath)
-		if err != nil {
-			return "", fmt.Errorf("failed to create note file %s: %w", fullPath, err)
+
+		existingPath := ""
+		if existing := d.GetEntryByName(entry.Name, entry.Ext); existing != nil && existing.IsDir == entry.IsDir {
+			existingPath = existing.FilePath()
+		} else {
+			err := d.writeNewEntry(entry, content)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, fs.ErrExist) {
+				return "", err
+			}
+			existingPath = entry.FilePath()
 		}
-		defer file.Close()
-		frontmatter := fmt.Sprintf("# %s\n\n", entry.Name)
 
-		if _, err := file.WriteString(frontmatter); err != nil {
-			return "", fmt.Errorf("failed to write frontmatter to note: %w", err)
+		slog.Debug("Entry already exists", "path", existingPath, "policy", policy)
+		switch policy {
+		case CollisionOpen:
+			return s.relPath(existingPath)
+		case CollisionAbort:
+			return "", fmt.Errorf("%w: %s", ErrEntryExists, existingPath)
 		}
 	}
 
@@ -82,26 +253,72 @@ func (s *EntryService) CreateEntry(d *Directory, entry *Entry) (string, error) {
 		return "", err
 	}
 
-	return entry.FilePath(), nil
+	return s.relPath(entry.FilePath())
+}
+
+func (d *Directory) writeNewEntry(entry *Entry, content func(name string) string) error {
+	fullPath := entry.FilePath()
+	slog.Debug("Creating at path", "fullPath", fullPath)
+
+	if entry.IsDir {
+		if err := d.files.mkdir(fullPath); err != nil {
+			return fmt.Errorf("failed to create note directory %s: %w", fullPath, err)
+		}
+		return nil
+	}
+
+	if err := d.files.createExclusive(fullPath, content(entry.Name)); err != nil {
+		return fmt.Errorf("failed to create note file %s: %w", fullPath, err)
+	}
+	return nil
+}
+
+func (s *EntryService) relPath(absPath string) (string, error) {
+	relPath, err := filepath.Rel(s.config.RootDir, absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s against root: %w", absPath, err)
+	}
+	return relPath, nil
+}
+
+func (s *EntryService) LaunchNoteEditor(filePath string) (Result, error) {
+	return s.LaunchNoteEditorAt(filePath, 0)
 }
 
-func (s *EntryService) LaunchNoteEditor(filePath string) error {
+// LaunchNoteEditorAt opens the note with the cursor on line, 0 leaves it where the editor puts it
+func (s *EntryService) LaunchNoteEditorAt(filePath string, line int) (Result, error) {
+	if s.IsDryRun() {
+		slog.Info("Dry run, not launching the note editor", "path", filePath)
+		return Result{Outcome: OutcomeNoop, Path: filePath}, nil
+	}
+
 	fullPath := filepath.Join(s.config.RootDir, filePath)
 
-	cmd := exec.Command("kitty", "--title", "The Garden Log", "-e", "nvim", fullPath)
+	args := []string{"--title", "The Garden Log", "-e", "nvim"}
+	if line > 0 {
+		args = append(args, fmt.Sprintf("+%d", line))
+	}
+	cmd := exec.Command("kitty", append(args, fullPath)...)
 
 	cmd.Dir = s.config.RootDir
 	cmd.Env = os.Environ()
 
 	err := cmd.Start()
 	if err != nil {
-		return fmt.Errorf("failed to launch note editor: %w", err)
+		return Result{}, fmt.Errorf("failed to launch note editor: %w", err)
 	}
 
-	return LaunchSuccessError{Message: "note editor launched successfully"}
+	slog.Debug("Note editor launched", "path", filePath, "line", line)
+	s.RecordVisit(filePath)
+	return Result{Outcome: OutcomeLaunched, Path: filePath}, nil
 }
 
-func (s *EntryService) LaunchDirectoryEditor(dirPath string) error {
+func (s *EntryService) LaunchDirectoryEditor(dirPath string) (Result, error) {
+	if s.IsDryRun() {
+		slog.Info("Dry run, not launching the directory editor", "path", dirPath)
+		return Result{Outcome: OutcomeNoop, Path: dirPath}, nil
+	}
+
 	fullPath := filepath.Join(s.config.RootDir, dirPath)
 
 	cmd := exec.Command("kitty", "-e", "tmux-sessionizer", fullPath)
@@ -109,10 +326,11 @@ func (s *EntryService) LaunchDirectoryEditor(dirPath string) error {
 
 	err := cmd.Start()
 	if err != nil {
-		return fmt.Errorf("failed to launch directory editor: %w", err)
+		return Result{}, fmt.Errorf("failed to launch directory editor: %w", err)
 	}
 
-	return LaunchSuccessError{Message: "directory editor launched successfully"}
+	slog.Debug("Directory editor launched", "path", dirPath)
+	return Result{Outcome: OutcomeLaunched, Path: dirPath}, nil
 }
 
 func (s *EntryService) CreateEntryFromUserInput(d *Directory, name string, isDir bool) (string, error) {
@@ -148,6 +366,11 @@ func (s *EntryService) CreateEntryFromTemplate(d *Directory, name string, templa
 		name = time.Now().Format("2006-01-02")
 	}
 
+	content, err := s.templateContent(templatePath, time.Now())
+	if err != nil {
+		return "", err
+	}
+
 	entry := &Entry{
 		Name:       name,
 		EntryIndex: d.NewFileIndex(),
@@ -156,24 +379,56 @@ func (s *EntryService) CreateEntryFromTemplate(d *Directory, name string, templa
 		ParentPath: d.AbsPath,
 	}
 
-	filePath, err := s.CreateEntry(d, entry)
+	d, err = s.noteDirectory(d, entry)
 	if err != nil {
 		return "", err
 	}
+	return s.createEntry(d, entry, content, s.config.OnCollision)
+}
 
+// RenderTemplate reads a template relative to the root and fills in its placeholders under a title heading.
+// Every template is rendered this way, the README lists the placeholders and unknown ones are left as they are.
+func (s *EntryService) RenderTemplate(templatePath string, title string, t time.Time) (string, error) {
+	content, err := s.templateContent(templatePath, t)
+	if err != nil {
+		return "", err
+	}
+	return content(title), nil
+}
+
+// templateContent reads a template once and renders it for whichever title the note ends up with
+func (s *EntryService) templateContent(templatePath string, t time.Time) (func(title string) string, error) {
 	absTemplatePath := filepath.Join(s.config.RootDir, templatePath)
-	templateContent, err := os.ReadFile(absTemplatePath)
+	templateContent, err := s.files.readFile(absTemplatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file %s: %w", absTemplatePath, err)
+	}
+
+	return func(title string) string {
+		return titleHeading(title) + RenderPlaceholders(string(templateContent), TemplateVars(title, t), t)
+	}, nil
+}
+
+// ResolveTemplate finds a template by name in the template directory, ignoring its index and extension
+func (s *EntryService) ResolveTemplate(name string) (string, error) {
+	dir, err := s.LoadDirectory(s.config.TemplateDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to read template file %s: %w", absTemplatePath, err)
+		return "", err
 	}
 
-	frontmatter := fmt.Sprintf("# %s\n\n", entry.Name)
-	finalContent := frontmatter + string(templateContent)
+	if entry := dir.GetEntryByFilename(name); entry != nil && !entry.IsDir {
+		return filepath.Join(dir.Path, entry.String()), nil
+	}
 
-	err = os.WriteFile(filePath, []byte(finalContent), 0644)
+	_, cleanName, err := parseEntryName(name)
 	if err != nil {
-		return "", fmt.Errorf("failed to write template content to %s: %w", filePath, err)
+		return "", err
+	}
+	for _, entry := range dir.Entries {
+		if !entry.IsDir && entry.Name == cleanName {
+			return filepath.Join(dir.Path, entry.String()), nil
+		}
 	}
 
-	return filePath, nil
+	return "", fmt.Errorf("template %q %w in %s", name, ErrNotFound, s.config.TemplateDir)
 }
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestService builds a garden in a temp directory. Paths are relative to the root, those ending in / are
+// directories and a .index file marks its directory as indexed. State and cache files stay in the test too.
+func newTestService(t *testing.T, paths ...string) *EntryService {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("GARDEN_LOGGER_STATE", t.TempDir())
+	t.Setenv("GARDEN_LOGGER_CACHE", t.TempDir())
+
+	for _, path := range paths {
+		absPath := filepath.Join(root, path)
+		if strings.HasSuffix(path, "/") {
+			if err := os.MkdirAll(absPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := ""
+		if filepath.Ext(path) == ".md" {
+			content = "# " + stripIndexPrefix(strings.TrimSuffix(filepath.Base(path), ".md")) + "\n"
+		}
+		if err := os.WriteFile(absPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewNotesService(&Config{RootDir: root})
+}
+
+// gardenFiles lists every file and directory below the root, directories ending in /
+func gardenFiles(t *testing.T, s *EntryService) []string {
+	t.Helper()
+	var paths []string
+	err := filepath.WalkDir(s.config.RootDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || path == s.config.RootDir {
+			return err
+		}
+		rel, _ := filepath.Rel(s.config.RootDir, path)
+		if d.IsDir() {
+			rel += "/"
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func TestCreateEntryCollisions(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   CollisionPolicy
+		existing []string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:   "no collision",
+			policy: CollisionAbort,
+			want:   "01. Notes/01. Foo.md",
+		},
+		{
+			name:     "open existing",
+			policy:   CollisionOpen,
+			existing: []string{"01. Notes/01. Foo.md"},
+			want:     "01. Notes/01. Foo.md",
+		},
+		{
+			name:     "abort",
+			policy:   CollisionAbort,
+			existing: []string{"01. Notes/01. Foo.md"},
+			wantErr:  ErrEntryExists,
+		},
+		{
+			name:     "suffix",
+			policy:   CollisionSuffix,
+			existing: []string{"01. Notes/01. Foo.md"},
+			want:     "01. Notes/02. Foo (2).md",
+		},
+		{
+			name:     "suffix past earlier suffixes",
+			policy:   CollisionSuffix,
+			existing: []string{"01. Notes/01. Foo.md", "01. Notes/02. Foo (2).md"},
+			want:     "01. Notes/03. Foo (3).md",
+		},
+		{
+			name:     "directory does not collide with note",
+			policy:   CollisionAbort,
+			existing: []string{"01. Notes/01. Foo/"},
+			want:     "01. Notes/02. Foo.md",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, append([]string{".index", "01. Notes/.index"}, tt.existing...)...)
+			s.config.OnCollision = tt.policy
+			d, err := s.LoadDirectory("01. Notes")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.CreateEntryFromUserInput(d, "Foo", false)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateEntryFromUserInput() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateEntryFromUserInput() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CreateEntryFromUserInput() = %q, want %q", got, tt.want)
+			}
+
+			content, err := os.ReadFile(filepath.Join(s.config.RootDir, got))
+			if err != nil {
+				t.Fatal(err)
+			}
+			heading := "# " + stripIndexPrefix(strings.TrimSuffix(filepath.Base(got), ".md")) + "\n"
+			if !strings.HasPrefix(string(content), heading) {
+				t.Errorf("content = %q, want it to start with %q", content, heading)
+			}
+		})
+	}
+}
+
+func TestCreateEntryFromTemplateTitlesSuffixedNote(t *testing.T) {
+	s := newTestService(t, ".index", "01. Notes/.index", "01. Notes/01. Foo.md", "Templates/Meeting.md")
+	s.config.OnCollision = CollisionSuffix
+	if err := os.WriteFile(filepath.Join(s.config.RootDir, "Templates/Meeting.md"), []byte("About {{title}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := s.LoadDirectory("01. Notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.CreateEntryFromTemplate(d, "Foo", "Templates/Meeting.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(s.config.RootDir, got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Foo (2)\n\nAbout Foo (2)\n"; string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	s := newTestService(t, "Templates/Meeting.md")
+	template := "---\ncreated: {{date}}\n---\n{{time}} {{title}}, week {{WW}}, {{unknown}}\n"
+	if err := os.WriteFile(filepath.Join(s.config.RootDir, "Templates/Meeting.md"), []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.RenderTemplate("Templates/Meeting.md", "Standup", time.Date(2026, 10, 19, 9, 30, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Standup\n\n---\ncreated: 2026-10-19\n---\n09:30 Standup, week 43, {{unknown}}\n"; got != want {
+		t.Errorf("RenderTemplate() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// frecencyHalfLife is how long it takes a visit to count half as much
+const frecencyHalfLife = 7 * 24 * time.Hour
+
+// maxVisits caps the state file, the notes with the lowest frecency are forgotten first
+const maxVisits = 500
+
+// gardenState is what the tool remembers about a garden between runs. It lives outside the garden
+// and paths in it are index-free so reordering doesn't lose anything.
+type gardenState struct {
+	Visits map[string]*Visit `json:"visits"`
+	// Pins are numbered bookmarks, numbers stay the same when other pins are removed
+	Pins []storedPin `json:"pins"`
+	// Session is where the menu was left, restored on the next launch
+	Session *Session `json:"session,omitempty"`
+
+	path string
+}
+
+// Visit counts how often a note was opened or created and when that last happened
+type Visit struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+// Frecency weighs how often a note is visited by how recently, halving every frecencyHalfLife
+func (v *Visit) Frecency(now time.Time) float64 {
+	age := now.Sub(v.Last).Hours()
+	return float64(v.Count) * math.Pow(0.5, math.Max(age, 0)/frecencyHalfLife.Hours())
+}
+
+// Session is the directory the menu was left in and the entry last chosen there, both without indexes
+type Session struct {
+	Dir       string `json:"dir"`
+	Selection string `json:"selection"`
+}
+
+// RecentNote is a visited note that still exists, Path is relative to the root
+type RecentNote struct {
+	Path  string
+	Visit Visit
+	Score float64
+}
+
+// StateDir returns the directory for what the tool remembers between runs, honouring GARDEN_LOGGER_STATE
+func StateDir() (string, error) {
+	if path := os.Getenv("GARDEN_LOGGER_STATE"); path != "" {
+		return path, nil
+	}
+	if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+		return filepath.Join(stateHome, "garden-logger"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate state directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "garden-logger"), nil
+}
+
+// rootFileName gives every root its own file in a directory shared by all of them
+func rootFileName(root string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(root))
+	return fmt.Sprintf("%016x.json", hash.Sum64())
+}
+
+func (s *EntryService) loadState() (*gardenState, error) {
+	stateDir, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &gardenState{path: filepath.Join(stateDir, rootFileName(s.config.RootDir))}
+	data, err := os.ReadFile(state.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read state file %s: %w", state.path, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			slog.Debug("State file is unreadable, starting over", "path", state.path, "error", err)
+		}
+	}
+
+	if state.Visits == nil {
+		state.Visits = map[string]*Visit{}
+	}
+	// Older state files numbered pins by their position
+	for i := range state.Pins {
+		if state.Pins[i].Number == 0 {
+			state.Pins[i].Number = i + 1
+		}
+	}
+	return state, nil
+}
+
+func (st *gardenState) save() error {
+	return saveJSON(st.path, st)
+}
+
+// saveJSON writes files the tool keeps outside the garden, like the state file and search index.
+// They're renamed into place so a crash never leaves half a file, and aren't recorded as changes.
+func saveJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	return replaceFile(path, data, 0644)
+}
+
+// RecordVisit remembers that a note was opened or created. Failing to is logged rather than returned,
+// it should never stop a note from opening.
+func (s *EntryService) RecordVisit(notePath string) {
+	if s.IsDryRun() {
+		return
+	}
+
+	state, err := s.loadState()
+	if err != nil {
+		slog.Debug("Failed to record visit", "path", notePath, "error", err)
+		return
+	}
+
+	now := time.Now()
+	key := StripIndexes(notePath)
+	visit, ok := state.Visits[key]
+	if !ok {
+		visit = &Visit{}
+		state.Visits[key] = visit
+	}
+	visit.Count++
+	visit.Last = now
+
+	if len(state.Visits) > maxVisits {
+		keys := make([]string, 0, len(state.Visits))
+		for key := range state.Visits {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			return cmp.Compare(state.Visits[b].Frecency(now), state.Visits[a].Frecency(now))
+		})
+		for _, key := range keys[maxVisits:] {
+			delete(state.Visits, key)
+		}
+	}
+
+	if err := state.save(); err != nil {
+		slog.Debug("Failed to record visit", "path", notePath, "error", err)
+		return
+	}
+	slog.Debug("Recorded visit", "path", key, "count", visit.Count)
+}
+
+// frecencies scores every visited note by its index-free path, an unreadable state file scores nothing
+func (s *EntryService) frecencies() map[string]float64 {
+	state, err := s.loadState()
+	if err != nil {
+		slog.Debug("Failed to load visits", "error", err)
+		return nil
+	}
+
+	now := time.Now()
+	scores := map[string]float64{}
+	for key, visit := range state.Visits {
+		scores[key] = visit.Frecency(now)
+	}
+	return scores
+}
+
+// RecentNotes returns visited notes that still exist and aren't ignored, highest frecency first
+func (s *EntryService) RecentNotes(limit int) ([]RecentNote, error) {
+	state, err := s.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var recent []RecentNote
+	for key, visit := range state.Visits {
+		notePath, found, err := s.FindPath(key)
+		if err != nil || !found || s.isIgnored(notePath) {
+			continue
+		}
+		if _, entry, err := s.loadEntry(notePath); err != nil || entry.IsDir {
+			continue
+		}
+		recent = append(recent, RecentNote{Path: notePath, Visit: *visit, Score: visit.Frecency(now)})
+	}
+
+	slices.SortFunc(recent, func(a, b RecentNote) int {
+		if order := cmp.Compare(b.Score, a.Score); order != 0 {
+			return order
+		}
+		return b.Visit.Last.Compare(a.Visit.Last)
+	})
+	if limit > 0 && len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent, nil
+}
+
+// rankByFrecency orders paths by their scores from frecencies, keeping the order of unvisited ones
+func rankByFrecency(paths []string, scores map[string]float64) {
+	slices.SortStableFunc(paths, func(a, b string) int {
+		return cmp.Compare(scores[StripIndexes(b)], scores[StripIndexes(a)])
+	})
+}
+
+// SaveSession remembers the directory the menu is leaving and the entry chosen in it
+func (s *EntryService) SaveSession(dirPath string, selection string) error {
+	if s.IsDryRun() {
+		return nil
+	}
+
+	state, err := s.loadState()
+	if err != nil {
+		return err
+	}
+	state.Session = &Session{Dir: StripIndexes(dirPath), Selection: stripIndexPrefix(selection)}
+	return state.save()
+}
+
+// LoadSession returns the saved directory and selection as they're named now, ok is false when there's
+// nothing to restore or the directory is gone
+func (s *EntryService) LoadSession() (dirPath string, selection string, ok bool) {
+	state, err := s.loadState()
+	if err != nil || state.Session == nil {
+		return "", "", false
+	}
+
+	dirPath, found, err := s.FindPath(state.Session.Dir)
+	if err != nil || !found {
+		return "", "", false
+	}
+	dir, err := s.LoadDirectory(dirPath)
+	if err != nil {
+		return "", "", false
+	}
+
+	if state.Session.Selection != "" {
+		if entry := dir.FindEntry(state.Session.Selection); entry != nil {
+			selection = entry.String()
+		}
+	}
+	return dirPath, selection, true
+}
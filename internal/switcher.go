@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+const breadcrumbSeparator = " › "
+
+// Breadcrumb shows a path without indexes or the .md extension, like Projects › Garden Logger › Ideas
+func Breadcrumb(entryPath string) string {
+	components := strings.Split(strings.TrimSuffix(StripIndexes(entryPath), ".md"), string(filepath.Separator))
+	return strings.Join(components, breadcrumbSeparator)
+}
+
+// isIgnored matches the config's ignore patterns against the end of an entry's index-free path, so "Templates"
+// matches every entry named Templates and "Journal/*.md" the notes directly inside any Journal. Patterns starting
+// with / only match from the root.
+func (s *EntryService) isIgnored(entryPath string) bool {
+	components := strings.Split(StripIndexes(entryPath), string(filepath.Separator))
+	for _, pattern := range s.config.Ignore {
+		anchored := strings.HasPrefix(pattern, "/")
+		pattern = strings.Trim(pattern, "/")
+		depth := strings.Count(pattern, "/") + 1
+		if depth > len(components) || (anchored && depth != len(components)) {
+			continue
+		}
+
+		tail := filepath.Join(components[len(components)-depth:]...)
+		if matched, _ := filepath.Match(pattern, tail); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// walkNotes calls fn for every note in the garden, leaving out ignored notes and everything in ignored directories
+func (s *EntryService) walkNotes(fn func(notePath string, entry *Entry) error) error {
+	return s.WalkGarden(func(entryPath string, entry *Entry) error {
+		if s.isIgnored(entryPath) {
+			slog.Debug("Ignoring entry", "path", entryPath)
+			if entry.IsDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir {
+			return nil
+		}
+		return fn(entryPath, entry)
+	})
+}
+
+// ListNotes returns the path of every note that isn't ignored, in garden order
+func (s *EntryService) ListNotes() ([]string, error) {
+	var notePaths []string
+	err := s.walkNotes(func(notePath string, entry *Entry) error {
+		notePaths = append(notePaths, notePath)
+		return nil
+	})
+	return notePaths, err
+}
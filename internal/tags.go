@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// inlineTagPattern matches #tag and nested #area/health after whitespace or at the start of a line.
+// ( isn't a boundary, in [see](#setup) the # starts an anchor.
+var inlineTagPattern = regexp.MustCompile(`(?:^|[\s\[,])#([\p{L}\p{N}_/-]+)`)
+
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// ParseTags returns the frontmatter tags and inline #tags of a note, skipping code, without # and deduplicated ignoring case
+func ParseTags(content string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	addTag := func(tag string) {
+		tag = strings.Trim(strings.TrimPrefix(tag, "#"), "/")
+		// #123 is an issue number or a heading anchor, not a tag
+		if tag == "" || !strings.ContainsFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) }) {
+			return
+		}
+		if key := strings.ToLower(tag); !seen[key] {
+			seen[key] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	meta, err := ParseFrontmatter(content)
+	if err != nil {
+		slog.Debug("Ignoring invalid frontmatter tags", "error", err)
+	}
+	for _, tag := range meta.Tags() {
+		addTag(tag)
+	}
+
+	proseLines(content, func(_ int, line string) {
+		for _, match := range inlineTagPattern.FindAllStringSubmatch(line, -1) {
+			addTag(match[1])
+		}
+	})
+	return tags
+}
+
+// tagMatches reports whether tag is want or nested under it, ignoring case
+func tagMatches(tag string, want string) bool {
+	tag, want = strings.ToLower(tag), strings.ToLower(want)
+	return tag == want || strings.HasPrefix(tag, want+"/")
+}
+
+// noteTags maps every note with tags to them
+func (s *EntryService) noteTags() (map[string][]string, error) {
+	docs, err := s.indexedDocs()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string][]string{}
+	for notePath, doc := range docs {
+		if len(doc.Tags) > 0 {
+			tags[notePath] = doc.Tags
+		}
+	}
+	return tags, nil
+}
+
+// Tags lists every tag in the garden by name with the number of notes carrying it.
+// Nested tags also count towards their parents, so area counts the notes tagged area/health.
+func (s *EntryService) Tags() ([]TagCount, error) {
+	tags, err := s.noteTags()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]*TagCount{}
+	for _, notePath := range slices.Sorted(maps.Keys(tags)) {
+		counted := map[string]bool{}
+		for _, tag := range tags[notePath] {
+			parts := strings.Split(tag, "/")
+			for i := range parts {
+				name := strings.Join(parts[:i+1], "/")
+				key := strings.ToLower(name)
+				if counted[key] {
+					continue
+				}
+				counted[key] = true
+
+				if count, ok := counts[key]; ok {
+					count.Count++
+				} else {
+					counts[key] = &TagCount{Tag: name, Count: 1}
+				}
+			}
+		}
+	}
+
+	var result []TagCount
+	for _, key := range slices.Sorted(maps.Keys(counts)) {
+		result = append(result, *counts[key])
+	}
+	return result, nil
+}
+
+// TaggedNotes returns the notes tagged with tag or a tag nested under it, in garden order
+func (s *EntryService) TaggedNotes(tag string) ([]string, error) {
+	tag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "#"), "/")
+	if tag == "" {
+		return nil, fmt.Errorf("%w: empty tag", ErrValidation)
+	}
+
+	tags, err := s.noteTags()
+	if err != nil {
+		return nil, err
+	}
+
+	var notePaths []string
+	for notePath, noteTags := range tags {
+		if slices.ContainsFunc(noteTags, func(noteTag string) bool { return tagMatches(noteTag, tag) }) {
+			notePaths = append(notePaths, notePath)
+		}
+	}
+	slices.Sort(notePaths)
+	return notePaths, nil
+}
@@ -0,0 +1,44 @@
+package internal
+
+import "path/filepath"
+
+type TreeNode struct {
+	Entry     *Entry
+	Path      string
+	IsIndexed bool
+	Notes     int
+	Children  []*TreeNode
+}
+
+// BuildTree loads dirPath and its subdirectories down to depth levels, depth < 0 means unlimited.
+// Note counts always cover the whole subtree, even below depth.
+func (s *EntryService) BuildTree(dirPath string, depth int, dirsOnly bool) (*TreeNode, error) {
+	dir, err := s.LoadDirectory(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &TreeNode{Path: dir.Path, IsIndexed: dir.IsIndexed}
+	for _, entry := range dir.Entries {
+		child := &TreeNode{Entry: entry, Path: filepath.Join(dir.Path, entry.String())}
+		if entry.IsDir {
+			subtree, err := s.BuildTree(child.Path, depth-1, dirsOnly)
+			if err != nil {
+				return nil, err
+			}
+			child.IsIndexed = subtree.IsIndexed
+			child.Notes = subtree.Notes
+			if depth != 1 {
+				child.Children = subtree.Children
+			}
+			root.Notes += subtree.Notes
+		} else {
+			root.Notes++
+			if dirsOnly {
+				continue
+			}
+		}
+		root.Children = append(root.Children, child)
+	}
+	return root, nil
+}